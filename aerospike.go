@@ -3,108 +3,281 @@ package aerospike
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/sdk/database/dbplugin"
 	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
 	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
-type aerospikeCreationStatement struct {
-	Roles []string `json:"roles"`
-}
-
 const aerospikeTypeName = "aerospike"
 
-var _ dbplugin.Database = &Aerospike{}
-
 // Aerospike is an implementation of Database interface.
 type Aerospike struct {
 	*aerospikeConnectionProducer
 	credsutil.CredentialsProducer
+
+	statementParser StatementParser
+	statementCache  map[[sha256.Size]byte]aerospikeCreationStatement
+	hooks           Hooks
 }
 
-// New returns a new Aerospike instance.
-func New() (interface{}, error) {
-	db := new()
+// New returns a new Aerospike instance, customized by opts.
+func New(opts ...Option) (interface{}, error) {
+	db := new(opts...)
 	// Wrap the plugin with middleware to sanitize errors
 	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.secretValues)
 	return dbType, nil
 }
 
-func new() *Aerospike {
+// NewWithClient returns a new Aerospike instance, customized by opts, which
+// uses client for all its operations instead of connecting to the cluster
+// itself. client is owned by the caller and is never closed by the plugin;
+// host and username are then optional in the config given to Init.
+func NewWithClient(client Client, opts ...Option) (interface{}, error) {
+	if client == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+
+	db := new(opts...)
+	db.client = client
+	db.clientProvided = true
+	db.gauges.setClient(client)
+
+	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.secretValues)
+	return dbType, nil
+}
+
+func new(opts ...Option) *Aerospike {
 	connProducer := &aerospikeConnectionProducer{}
 	connProducer.Type = aerospikeTypeName
+	connProducer.logger = newLogger(false)
+	connProducer.lockWaits = newWaitHistogram()
+	connProducer.counters = newOpCounters()
+	connProducer.gauges = newGauges()
 
 	credsProducer := &credsutil.SQLCredentialsProducer{
 		DisplayNameLen: 15,
 		RoleNameLen:    15,
-		// See https://www.aerospike.com/docs/guide/limitations.html
-		UsernameLen: 63,
-		Separator:   "-",
+		UsernameLen:    maxUsernameLength,
+		Separator:      "-",
 	}
 
-	return &Aerospike{
+	db := &Aerospike{
 		aerospikeConnectionProducer: connProducer,
 		CredentialsProducer:         credsProducer,
+		statementParser:             jsonStatementParser{},
+	}
+
+	for _, opt := range opts {
+		opt(db)
 	}
+
+	return db
+}
+
+func newLogger(jsonFormat bool) hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:       aerospikeTypeName,
+		Output:     os.Stderr,
+		JSONFormat: jsonFormat,
+	})
 }
 
-// Run instantiates an Aerospike object, and runs the RPC server for the plugin.
-func Run(apiTLSConfig *api.TLSConfig) error {
-	dbType, err := New()
+// Connect returns a client connected to the cluster described by conf, which
+// holds the same fields as the plugin configuration. It lets companion tools
+// reuse the plugin's connection handling. Unlike Init, it only connects: it
+// does not bootstrap the admin user nor start any background job.
+func Connect(ctx context.Context, conf map[string]interface{}) (*aerospike.Client, error) {
+	c, err := connect(ctx, conf)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return c.client.(*aerospike.Client), nil
+}
+
+// connect returns a producer configured with conf and connected to the
+// cluster, as described by Connect.
+func connect(ctx context.Context, conf map[string]interface{}) (*aerospikeConnectionProducer, error) {
+	c := &aerospikeConnectionProducer{
+		Type:   aerospikeTypeName,
+		logger: newLogger(false),
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.parseConfig(conf); err != nil {
+		return nil, &kindError{kind: ErrInvalidConfig, err: err}
 	}
+	c.Initialized = true
 
-	dbplugin.Serve(dbType.(dbplugin.Database), api.VaultPluginTLSProvider(apiTLSConfig))
+	if err := c.verifyConnection(ctx); err != nil {
+		return nil, fmt.Errorf("error verifying connection: %w", err)
+	}
+
+	// The client is not created by the verification when verify_mode is info
+	if _, err := c.Connection(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run instantiates an Aerospike object customized by opts, and runs the RPC
+// server for the plugin.
+func Run(apiTLSConfig *api.TLSConfig, opts ...Option) error {
+	db := new(opts...)
+	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.secretValues)
+
+	conf := dbplugin.ServeConfig(dbType, api.VaultPluginTLSProvider(apiTLSConfig))
+	if conf == nil {
+		return errors.New("unable to configure the plugin server")
+	}
+
+	startDebugListener(db.logger, db.lockWaits, db.counters, db.gauges)
+	closeOnTerminate(db)
+
+	health := newHealthService(db)
+	defer health.stop()
+
+	conf.GRPCServer = func(opts []grpc.ServerOption) *grpc.Server {
+		return plugin.DefaultGRPCServer(append(opts,
+			grpc.UnaryInterceptor(health.unaryInterceptor),
+			grpc.StreamInterceptor(health.streamInterceptor),
+		))
+	}
+
+	plugin.Serve(conf)
 
 	return nil
 }
 
-// Type returns the TypeName for this backend
-func (a *Aerospike) Type() (string, error) {
-	return aerospikeTypeName, nil
+// logOperation logs the outcome of an operation. username and err are
+// pointers so that it can be deferred before their final value is known. When
+// the context carries a correlation ID, it is included in the log lines and
+// appended to the returned error.
+func (a *Aerospike) logOperation(ctx context.Context, operation string, username *string, start time.Time, err *error) {
+	duration := time.Since(start)
+	a.counters.record(operation, *err)
+
+	args := []interface{}{
+		"operation", operation,
+		"username", *username,
+		"duration", duration,
+	}
+
+	if id := correlationID(ctx); id != "" {
+		args = append(args, "correlation_id", id)
+		if *err != nil {
+			*err = fmt.Errorf("%w (correlation ID %s)", *err, id)
+		}
+	}
+
+	if a.slowOperationThreshold > 0 && duration > a.slowOperationThreshold {
+		slowArgs := append([]interface{}{}, args...)
+		var aerr *aerospike.AerospikeError
+		if errors.As(*err, &aerr) && aerr.Node != nil {
+			slowArgs = append(slowArgs, "node", aerr.Node.GetName())
+		}
+		a.logger.Warn("slow operation", append(slowArgs, "threshold", a.slowOperationThreshold)...)
+	}
+
+	if *err != nil {
+		a.logger.Error("operation failed", append(args, "outcome", "failure", "error", *err)...)
+		return
+	}
+
+	a.logger.Info("operation succeeded", append(args, "outcome", "success")...)
+}
+
+// correlationIDKeys are the gRPC metadata keys that may hold a request or
+// correlation ID, in order of preference.
+var correlationIDKeys = []string{"x-correlation-id", "correlation-id", "x-request-id", "request-id"}
+
+// correlationID returns the correlation ID found in the incoming gRPC metadata
+// of ctx, if any.
+func correlationID(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, key := range correlationIDKeys {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+
+	return ""
 }
 
-func (a *Aerospike) getConnection(ctx context.Context) (*aerospike.Client, error) {
+func (a *Aerospike) getConnection(ctx context.Context) (Client, error) {
 	client, err := a.Connection(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.(*aerospike.Client), nil
+	return client.(Client), nil
 }
 
-// CreateUser generates the username/password on the underlying Aerospike
-// secret backend as instructed by the CreationStatement provided. The creation
-// statement is a JSON blob that has a an array of roles.
+// createUser generates the username/password on the underlying Aerospike
+// secret backend as instructed by the first of the creation statements. The
+// creation statement is a JSON blob that has a an array of roles, and may
+// override the timeout and retries of the operation, unless a custom
+// StatementParser was given.
 //
 // JSON Example:
-//  { roles": ["read", "user-admin"] }
-func (a *Aerospike) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+//  { roles": ["read", "user-admin"], "timeout_ms": 5000 }
+func (a *Aerospike) createUser(ctx context.Context, creation []string, displayName, roleName string) (username string, password string, err error) {
+	defer a.logOperation(ctx, "create_user", &username, time.Now(), &err)
+
 	// Grab the lock
-	a.Lock()
+	a.lockTimed()
 	defer a.Unlock()
+	defer a.releaseConnection()
+
+	var overrides statementOverrides
+	_, defaultDialect := a.statementParser.(jsonStatementParser)
+	if defaultDialect {
+		if overrides, err = parseOverrides(creation); err != nil {
+			return "", "", err
+		}
+	}
 
-	statements = dbutil.StatementCompatibilityHelper(statements)
+	ctx, cancel := withTimeout(ctx, overrides.timeout(a.createTimeout))
+	defer cancel()
+	ctx = overrides.context(ctx)
 
-	if len(statements.Creation) == 0 {
+	if err := a.checkWritable(); err != nil {
+		return "", "", err
+	}
+
+	if err := a.checkCreatable(); err != nil {
+		return "", "", err
+	}
+
+	if len(creation) == 0 {
 		return "", "", dbutil.ErrEmptyCreationStatement
 	}
 
-	client, err := a.getConnection(ctx)
+	username, err = a.generateUsername(displayName, roleName)
 	if err != nil {
 		return "", "", err
 	}
 
-	username, err = a.GenerateUsername(usernameConfig)
-	if err != nil {
+	if err := a.checkReserved(username); err != nil {
 		return "", "", err
 	}
 
@@ -113,84 +286,276 @@ func (a *Aerospike) CreateUser(ctx context.Context, statements dbplugin.Statemen
 		return "", "", err
 	}
 
-	// Unmarshal statements.CreationStatements into roles
-	var cs aerospikeCreationStatement
-	err = json.Unmarshal([]byte(statements.Creation[0]), &cs)
+	cs, err := a.parseCreationStatement(creation[0])
 	if err != nil {
 		return "", "", err
 	}
 
-	if len(cs.Roles) == 0 {
-		return "", "", fmt.Errorf("roles array is required in creation statement")
+	roles := cs.Roles
+	bundle, err := a.statementBundle(cs.Bundle)
+	if err != nil {
+		return "", "", err
+	}
+	if bundle != nil {
+		roles = withBundleRoles(roles, bundle)
 	}
 
-	if err := client.CreateUser(aerospike.NewAdminPolicy(), username, password, cs.Roles); err != nil {
-		return "", "", err
+	attempts := 0
+	err = overrides.retryPolicy(a.retry).do(ctx, func() error {
+		attempts++
+		client, err := a.getConnection(ctx)
+		if err != nil {
+			return err
+		}
+		if len(a.AllowedPrivileges) > 0 {
+			if err := a.checkPrivileges(client, roles); err != nil {
+				return err
+			}
+		}
+		if err := a.checkUserCap(client, adminPolicy(ctx)); err != nil {
+			return err
+		}
+		if bundle != nil && !a.roleBundlesApplied {
+			if err := a.applyRoleBundles(client, adminPolicy(ctx)); err != nil {
+				return err
+			}
+		}
+		if err := injectFault("create_user"); err != nil {
+			return err
+		}
+		err = client.CreateUser(adminPolicy(ctx), username, password, roles)
+		if attempts > 1 && isUserExists(err) {
+			// An attempt that timed out went through: the generated name
+			// is unique, so the user is the one created by this call
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		a.roleCache.clear()
+		return "", "", explainAdminError(err)
+	}
+
+	if a.VerifyNewUsers {
+		if err := a.verifyNewUser(ctx, username, password); err != nil {
+			return "", "", err
+		}
 	}
 
+	a.countDynamicUser(username, 1)
+	a.writeAuditRecord(ctx, username, aerospike.BinMap{
+		"username":     username,
+		"roles":        roles,
+		"role_name":    roleName,
+		"display_name": displayName,
+		"created":      auditTime(time.Now()),
+	})
+
+	a.fireEvent(ctx, eventUserCreated, a.hooks.OnUserCreated, username, roles)
+
 	return username, password, nil
 }
 
-// SetCredentials uses provided information to set/create a user in the
-// database. Unlike CreateUser, this method requires a username be provided and
-// uses the name given, instead of generating a name. This is used for creating
-// and setting the password of static accounts, as well as rolling back
-// passwords in the database in the event an updated database fails to save in
-// Vault's storage.
-func (a *Aerospike) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username, password string, err error) {
+// verifyNewUser checks that a user that was just created can log in, and read
+// from VerifyNamespace when it is set, as well as write to it when VerifyWrite
+// is set. Since the user takes a moment to reach every node, the checks are
+// repeated until they succeed, for at most the verification timeout. The user
+// is dropped if they never do, so that Vault never hands out credentials that
+// do not work. It must be called with the lock held, which is released while
+// connecting as the user.
+func (a *Aerospike) verifyNewUser(ctx context.Context, username, password string) error {
+	policy := a.credentialPolicy(username, password)
+	hosts := a.seedHosts()
+	namespace, write := a.VerifyNamespace, a.VerifyWrite
+	timeout, logger := a.verifyTimeout, a.logger
+
+	a.Unlock()
+	err := pollVerification(ctx, timeout, func() error {
+		err := loginAny(policy, hosts)
+		if err == nil && namespace != "" {
+			err = verifyAccess(logger, policy, hosts, namespace, write)
+		}
+		return err
+	})
+	a.lockTimed()
+	if err == nil {
+		return nil
+	}
+
+	dropErr := a.retry.do(ctx, func() error {
+		client, err := a.getConnection(ctx)
+		if err != nil {
+			return err
+		}
+		if err := injectFault("drop_user"); err != nil {
+			return err
+		}
+		return client.DropUser(adminPolicy(ctx), username)
+	})
+	if dropErr != nil {
+		a.logger.Error("unable to drop user that failed verification", "username", username, "error", dropErr)
+	}
+
+	return fmt.Errorf("error verifying new user: %w", err)
+}
+
+// setPassword sets the password of an existing user, after applying the
+// rotation statements. This is used for setting the password of static
+// accounts, as well as rolling back passwords in the database in the event an
+// updated database fails to save in Vault's storage.
+func (a *Aerospike) setPassword(ctx context.Context, rotation []string, username, password string) (err error) {
+	defer a.logOperation(ctx, "set_credentials", &username, time.Now(), &err)
+
 	// Grab the lock
-	a.Lock()
+	a.lockTimed()
 	defer a.Unlock()
+	defer a.releaseConnection()
+
+	ctx, cancel := withTimeout(ctx, a.passwordChangeTimeout)
+	defer cancel()
+
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := a.checkReserved(username); err != nil {
+		return err
+	}
 
-	client, err := a.getConnection(ctx)
+	if err := a.checkStaticAllowed(username); err != nil {
+		return err
+	}
+
+	rotations, err := parseRotationStatements(rotation)
 	if err != nil {
-		return "", "", err
+		return err
 	}
 
-	username = staticUser.Username
-	password = staticUser.Password
+	// Roles are updated before the password is changed, so that a failure
+	// does not leave Vault with a password it did not store
+	err = a.retry.do(ctx, func() error {
+		client, err := a.getConnection(ctx)
+		if err != nil {
+			return err
+		}
+		if err := checkUserExists(client, adminPolicy(ctx), username); err != nil {
+			return err
+		}
+		for _, rs := range rotations {
+			if err := applyRotationStatement(client, adminPolicy(ctx), rs); err != nil {
+				return err
+			}
+		}
+		// The client sends a set-password command when username is not the
+		// plugin's own user, and a change-password command otherwise
+		if err := injectFault("set_password"); err != nil {
+			return err
+		}
+		return client.ChangePassword(adminPolicy(ctx), username, password)
+	})
+	if err != nil {
+		return explainAdminError(err)
+	}
 
-	if err := client.ChangePassword(aerospike.NewAdminPolicy(), username, password); err != nil {
-		return "", "", err
+	if a.VerifyStaticUsers {
+		if err := a.verifyPropagation(ctx, username, password); err != nil {
+			return err
+		}
 	}
 
-	return username, password, nil
-}
+	a.fireEvent(ctx, eventPasswordChanged, a.hooks.OnPasswordChanged, username, nil)
 
-// RenewUser is not supported on Aerospike, so this is a no-op.
-func (a *Aerospike) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
-	// NOOP
 	return nil
 }
 
-// RevokeUser drops the specified user.
-func (a *Aerospike) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+// dropUser drops the specified user. The first of the revocation statements,
+// if any, may override the timeout and retries of the operation.
+func (a *Aerospike) dropUser(ctx context.Context, revocation []string, username string) (err error) {
+	defer a.logOperation(ctx, "revoke_user", &username, time.Now(), &err)
+
 	// Grab the lock
-	a.Lock()
+	a.lockTimed()
 	defer a.Unlock()
+	defer a.releaseConnection()
+
+	// Revocation statements of another dialect are left alone, like the
+	// creation statements
+	var overrides statementOverrides
+	if _, defaultDialect := a.statementParser.(jsonStatementParser); defaultDialect {
+		if overrides, err = parseOverrides(revocation); err != nil {
+			return err
+		}
+	}
 
-	client, err := a.getConnection(ctx)
-	if err != nil {
+	ctx, cancel := withTimeout(ctx, overrides.timeout(a.dropTimeout))
+	defer cancel()
+	ctx = overrides.context(ctx)
+
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := a.checkDroppable(username); err != nil {
 		return err
 	}
 
-	return client.DropUser(aerospike.NewAdminPolicy(), username)
+	err = overrides.retryPolicy(a.retry).do(ctx, func() error {
+		client, err := a.getConnection(ctx)
+		if err != nil {
+			return err
+		}
+		if err := injectFault("drop_user"); err != nil {
+			return err
+		}
+		return client.DropUser(adminPolicy(ctx), username)
+	})
+	if isUserNotFound(err) {
+		// Already dropped, most likely by a queued revocation: Vault would
+		// otherwise retry the revocation forever
+		a.logger.Info("user already dropped", "username", username)
+		a.forgetRevocation(username)
+		return nil
+	}
+	if err != nil {
+		// Vault retries failed revocations too, but only after long delays
+		a.queueRevocation(username, err)
+		return explainAdminError(err)
+	}
+
+	a.countDynamicUser(username, -1)
+	a.writeAuditRecord(ctx, username, aerospike.BinMap{
+		"username": username,
+		"revoked":  auditTime(time.Now()),
+	})
+
+	a.fireEvent(ctx, eventUserRevoked, a.hooks.OnUserRevoked, username, nil)
+
+	return nil
 }
 
-// RotateRootCredentials rotates the initial root database credentials. The new
-// root password will only be known by Vault.
-func (a *Aerospike) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+// rotateRoot rotates the initial root database credentials, and returns the
+// config to store. The new root password will only be known by Vault.
+func (a *Aerospike) rotateRoot(ctx context.Context) (config map[string]interface{}, err error) {
+	defer a.logOperation(ctx, "rotate_root_credentials", &a.Username, time.Now(), &err)
+
 	// Grab the lock
-	a.Lock()
+	a.lockTimed()
 	defer a.Unlock()
+	defer a.releaseConnection()
+
+	ctx, cancel := withTimeout(ctx, a.passwordChangeTimeout)
+	defer cancel()
+
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
 
 	if len(a.Username) == 0 || len(a.Password) == 0 {
 		return nil, errors.New("username and password are required to rotate")
 	}
 
-	client, err := a.getConnection(ctx)
-	if err != nil {
-		return nil, err
+	if a.PasswordFile != "" {
+		return nil, errors.New("cannot rotate when the password is read from password_file")
 	}
 
 	password, err := a.GeneratePassword()
@@ -198,12 +563,47 @@ func (a *Aerospike) RotateRootCredentials(ctx context.Context, statements []stri
 		return nil, err
 	}
 
-	if err := client.ChangePassword(aerospike.NewAdminPolicy(), a.Username, password); err != nil {
+	var client Client
+	err = a.retry.do(ctx, func() (err error) {
+		client, err = a.getConnection(ctx)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Close the database connection to ensure no new connections come in
-	//client.Close()
+	// The change is not retried: it is sent with the current password, which
+	// no longer works once a first attempt has taken effect. Since a.Username
+	// is the user the client is logged in as, the client sends a
+	// change-password command with the current password
+	err = injectFault("set_password")
+	if err == nil {
+		err = client.ChangePassword(adminPolicy(ctx), a.Username, password)
+	}
+	if err != nil && isTransient(err) {
+		// The change may have taken effect before the error, in which case
+		// only the new password works from now on
+		if probeErr := a.verifyLogin(a.Username, password); probeErr == nil {
+			a.logger.Warn("root password change failed but the new password works", "error", err)
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, explainAdminError(err)
+	}
+
+	// Switch to the new password and reconnect, so that no connection keeps
+	// using state derived from the old one
+	// The password was changed, so it must be returned to be stored by Vault
+	// even if reconnecting fails; the next operation will try again
+	a.Password = password
+	a.clientPolicy.Password = password
+	a.closeClient()
+	if _, err := a.Connection(ctx); err != nil {
+		a.logger.Warn("unable to reconnect after rotating the root password", "error", err)
+	}
+
+	a.fireEvent(ctx, eventRootRotated, a.hooks.OnRotateRoot, a.Username, nil)
 
 	a.RawConfig["password"] = password
 	return a.RawConfig, nil
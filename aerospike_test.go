@@ -0,0 +1,43 @@
+package aerospike
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+)
+
+// timeoutClient fails every password change with a timeout.
+type timeoutClient struct {
+	*countingClient
+	passwordChanges int
+}
+
+func (c *timeoutClient) ChangePassword(*aerospike.AdminPolicy, string, string) aerospike.Error {
+	c.passwordChanges++
+	return &aerospike.AerospikeError{ResultCode: types.TIMEOUT}
+}
+
+func TestRotateRootNotRetried(t *testing.T) {
+	client := &timeoutClient{countingClient: newCountingClient()}
+	dbRaw, err := NewWithClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := dbRaw.(dbplugin.Database)
+	defer db.Close()
+
+	conf := map[string]interface{}{"username": "admin", "password": "secret", "retry_max_attempts": 3, "retry_base_delay": "1ms"}
+	if _, err := db.Init(context.Background(), conf, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := db.RotateRootCredentials(context.Background(), nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if client.passwordChanges != 1 {
+		t.Errorf("expected a single password change, got %d", client.passwordChanges)
+	}
+}
@@ -0,0 +1,73 @@
+package aerospike
+
+import (
+	"context"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+const defaultAuditSet = "vault_audit"
+
+// RecordAdoption writes the audit record of username, an existing user
+// brought under Vault management as the static role roleName, to the cluster
+// described by conf, which holds the same fields as the plugin configuration.
+// The record follows the conventions of the records written by the plugin. It
+// reports whether the record was written, which is only done when
+// audit_namespace is set.
+func RecordAdoption(ctx context.Context, conf map[string]interface{}, username, roleName string, roles []string) (bool, error) {
+	c, err := connect(ctx, conf)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.AuditNamespace == "" {
+		return false, nil
+	}
+
+	err = c.putAuditRecord(ctx, username, aerospike.BinMap{
+		"username":  username,
+		"roles":     roles,
+		"role_name": roleName,
+		"adopted":   auditTime(time.Now()),
+	})
+	return err == nil, err
+}
+
+// writeAuditRecord writes bins to the audit record of username when
+// audit_namespace is set, creating the record or adding to it. Failures are
+// logged but do not fail the operation, which already succeeded.
+func (c *aerospikeConnectionProducer) writeAuditRecord(ctx context.Context, username string, bins aerospike.BinMap) {
+	if c.AuditNamespace == "" {
+		return
+	}
+
+	if err := c.putAuditRecord(ctx, username, bins); err != nil {
+		c.logger.Error("unable to write audit record", "username", username, "namespace", c.AuditNamespace, "set", c.AuditSet, "error", err)
+	}
+}
+
+// putAuditRecord writes bins to the audit record of username. It must be
+// called with the lock held.
+func (c *aerospikeConnectionProducer) putAuditRecord(ctx context.Context, username string, bins aerospike.BinMap) error {
+	return c.retry.do(ctx, func() error {
+		client, err := c.Connection(ctx)
+		if err != nil {
+			return err
+		}
+		key, err := aerospike.NewKey(c.AuditNamespace, c.AuditSet, username)
+		if err != nil {
+			return err
+		}
+		return client.(Client).Put(writePolicy(ctx), key, bins)
+	})
+}
+
+// auditTime formats t as stored in audit records.
+func auditTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
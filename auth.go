@@ -0,0 +1,74 @@
+package aerospike
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+)
+
+// Authentication modes.
+const (
+	authModeInternal = "internal"
+	authModeExternal = "external"
+	authModePKI      = "pki"
+)
+
+// parseAuthMode returns the client authentication mode for the auth_mode
+// parameter. An empty auth_mode selects internal authentication, with
+// detection of external authentication on failure.
+func parseAuthMode(mode string) (aerospike.AuthMode, error) {
+	switch mode {
+	case "", authModeInternal:
+		return aerospike.AuthModeInternal, nil
+	case authModeExternal:
+		return aerospike.AuthModeExternal, nil
+	case authModePKI:
+		return aerospike.AuthModePKI, nil
+	default:
+		return 0, fmt.Errorf("invalid auth_mode %q, must be %s, %s or %s", mode, authModeInternal, authModeExternal, authModePKI)
+	}
+}
+
+// isAuthError reports whether err is caused by the cluster rejecting the
+// credentials.
+func isAuthError(err error) bool {
+	var aerr aerospike.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	return aerr.Matches(
+		types.INVALID_USER,
+		types.INVALID_PASSWORD,
+		types.EXPIRED_PASSWORD,
+		types.INVALID_CREDENTIAL,
+		types.NOT_AUTHENTICATED,
+	)
+}
+
+// detectAuthMode runs connect with the client policy. When auth_mode is not
+// set and the credentials are rejected, it tries again with external
+// authentication if TLS is enabled, since the cluster only accepts it over
+// TLS, and keeps that mode if it works. Otherwise the returned error tells
+// which auth_mode may be needed.
+func (c *aerospikeConnectionProducer) detectAuthMode(connect func(*aerospike.ClientPolicy) error) error {
+	err := connect(c.clientPolicy)
+	if err == nil || c.AuthMode != "" || !isAuthError(err) {
+		return err
+	}
+
+	if c.clientPolicy.TlsConfig != nil {
+		policy := *c.clientPolicy
+		policy.AuthMode = aerospike.AuthModeExternal
+		if connect(&policy) == nil {
+			c.logger.Info("detected external authentication, set auth_mode to skip detection", "auth_mode", authModeExternal)
+			c.authMode = aerospike.AuthModeExternal
+			c.clientPolicy.AuthMode = aerospike.AuthModeExternal
+			return nil
+		}
+	}
+
+	return fmt.Errorf("authentication as %s failed; set auth_mode=%s if it is an external (LDAP) user, which requires TLS, or auth_mode=%s to authenticate with the client certificate: %w", c.Username, authModeExternal, authModePKI, err)
+}
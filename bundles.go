@@ -0,0 +1,98 @@
+package aerospike
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// roleBundle is a named set of roles, along with the quotas and whitelists of
+// those roles, defined once in the config and referenced by name by creation
+// statements.
+type roleBundle struct {
+	Roles []string `json:"roles"`
+	aerospikeRotationStatement
+}
+
+// parseRoleBundles parses the role_bundles parameter, given either as an
+// object or as a JSON string, mapping bundle names to their definition.
+func parseRoleBundles(raw interface{}) (map[string]roleBundle, error) {
+	data, ok := raw.(string)
+	if !ok {
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+
+	var bundles map[string]roleBundle
+	if err := json.Unmarshal([]byte(data), &bundles); err != nil {
+		return nil, fmt.Errorf("must be an object or a JSON string: %w", err)
+	}
+
+	for name, b := range bundles {
+		if len(b.Roles) == 0 {
+			return nil, fmt.Errorf("bundle %q has no roles", name)
+		}
+	}
+
+	return bundles, nil
+}
+
+// statementBundle returns the bundle referenced by name by a creation
+// statement, if any.
+func (c *aerospikeConnectionProducer) statementBundle(name string) (*roleBundle, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	b, ok := c.roleBundles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown bundle %q, it must be defined in role_bundles", ErrInvalidStatement, name)
+	}
+
+	return &b, nil
+}
+
+// applyRoleBundles applies the quotas and whitelists of every bundle to its
+// roles. This is done once per config: when it is written, if its connection
+// is verified, or else before the first user is created with a bundle. It
+// must be called with the lock held.
+func (c *aerospikeConnectionProducer) applyRoleBundles(client Client, policy *aerospike.AdminPolicy) error {
+	names := make([]string, 0, len(c.roleBundles))
+	for name := range c.roleBundles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := applyRotationStatement(client, policy, c.roleBundles[name].aerospikeRotationStatement); err != nil {
+			return fmt.Errorf("bundle %q: %w", name, err)
+		}
+	}
+
+	c.roleBundlesApplied = true
+	return nil
+}
+
+// withBundleRoles returns roles along with the roles of b that are not part
+// of it yet.
+func withBundleRoles(roles []string, b *roleBundle) []string {
+	result := append([]string{}, roles...)
+	for _, r := range b.Roles {
+		found := false
+		for _, existing := range result {
+			if existing == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, r)
+		}
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package aerospike
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoleBundles(t *testing.T) {
+	analytics := roleBundle{
+		Roles: []string{"read", "app-reader"},
+		aerospikeRotationStatement: aerospikeRotationStatement{
+			Quotas:     map[string]roleQuotas{"app-reader": {Read: 1000}},
+			Whitelists: map[string][]string{"app-reader": {"10.1.0.0/16"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		raw     interface{}
+		want    map[string]roleBundle
+		wantErr bool
+	}{
+		{
+			name: "JSON string",
+			raw:  `{"analytics": {"roles": ["read", "app-reader"], "quotas": {"app-reader": {"read": 1000}}, "whitelists": {"app-reader": ["10.1.0.0/16"]}}}`,
+			want: map[string]roleBundle{"analytics": analytics},
+		},
+		{
+			name: "object",
+			raw: map[string]interface{}{
+				"analytics": map[string]interface{}{
+					"roles":      []interface{}{"read", "app-reader"},
+					"quotas":     map[string]interface{}{"app-reader": map[string]interface{}{"read": 1000}},
+					"whitelists": map[string]interface{}{"app-reader": []interface{}{"10.1.0.0/16"}},
+				},
+			},
+			want: map[string]roleBundle{"analytics": analytics},
+		},
+		{
+			name: "roles only",
+			raw:  `{"reader": {"roles": ["read"]}}`,
+			want: map[string]roleBundle{"reader": {Roles: []string{"read"}}},
+		},
+		{name: "no roles", raw: `{"empty": {"quotas": {"read": {"read": 1}}}}`, wantErr: true},
+		{name: "invalid JSON", raw: `{"reader": `, wantErr: true},
+		{name: "not an object", raw: `["read"]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRoleBundles(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithBundleRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		want  []string
+	}{
+		{name: "no roles", want: []string{"read", "app-reader"}},
+		{name: "additional roles", roles: []string{"write"}, want: []string{"write", "read", "app-reader"}},
+		{name: "duplicate roles", roles: []string{"app-reader"}, want: []string{"app-reader", "read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withBundleRoles(tt.roles, &roleBundle{Roles: []string{"read", "app-reader"}})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package aerospike
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
+)
+
+// defaultCanaryRoles are the roles granted to the canary user when
+// canary_roles is not set.
+var defaultCanaryRoles = []string{"read"}
+
+// runCanary creates a short-lived user, logs in as it and drops it, logging
+// whether the whole cycle succeeded. It checks end to end that credentials can
+// be issued. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) runCanary() {
+	defer c.releaseConnection()
+
+	if c.Maintenance {
+		return
+	}
+
+	start := time.Now()
+	username, err := c.canary()
+	c.counters.record("canary", err)
+
+	if err != nil {
+		c.logger.Error("canary failed", "username", username, "duration", time.Since(start), "error", err)
+		return
+	}
+	c.logger.Info("canary succeeded", "username", username, "duration", time.Since(start))
+}
+
+func (c *aerospikeConnectionProducer) canary() (username string, err error) {
+	username, err = c.buildUsername("canary", "canary", "-", false)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := credsutil.RandomAlphaNumeric(20, true)
+	if err != nil {
+		return username, err
+	}
+
+	roles := c.CanaryRoles
+	if len(roles) == 0 {
+		roles = defaultCanaryRoles
+	}
+
+	client, err := c.Connection(context.Background())
+	if err != nil {
+		return username, err
+	}
+
+	if err := client.(Client).CreateUser(aerospike.NewAdminPolicy(), username, password, roles); err != nil {
+		return username, fmt.Errorf("unable to create canary user: %w", err)
+	}
+
+	err = c.verifyLogin(username, password)
+
+	if dropErr := client.(Client).DropUser(aerospike.NewAdminPolicy(), username); dropErr != nil {
+		if err == nil {
+			err = fmt.Errorf("unable to drop canary user: %w", dropErr)
+		} else {
+			c.logger.Error("unable to drop canary user", "username", username, "error", dropErr)
+		}
+	}
+
+	return username, err
+}
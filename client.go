@@ -0,0 +1,37 @@
+package aerospike
+
+import (
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// Client is the part of *aerospike.Client used by the plugin's operations. It
+// lets embedders and tests provide their own client with NewWithClient.
+type Client interface {
+	CreateUser(policy *aerospike.AdminPolicy, user string, password string, roles []string) aerospike.Error
+	DropUser(policy *aerospike.AdminPolicy, user string) aerospike.Error
+	ChangePassword(policy *aerospike.AdminPolicy, user string, password string) aerospike.Error
+	QueryUser(policy *aerospike.AdminPolicy, user string) (*aerospike.UserRoles, aerospike.Error)
+	QueryUsers(policy *aerospike.AdminPolicy) ([]*aerospike.UserRoles, aerospike.Error)
+	QueryRole(policy *aerospike.AdminPolicy, role string) (*aerospike.Role, aerospike.Error)
+	SetWhitelist(policy *aerospike.AdminPolicy, roleName string, whitelist []string) aerospike.Error
+	SetQuotas(policy *aerospike.AdminPolicy, roleName string, readQuota, writeQuota uint32) aerospike.Error
+	Put(policy *aerospike.WritePolicy, key *aerospike.Key, binMap aerospike.BinMap) aerospike.Error
+	Stats() (map[string]interface{}, aerospike.Error)
+	GetNodes() []*aerospike.Node
+	IsConnected() bool
+	Close()
+}
+
+var _ Client = &aerospike.Client{}
+
+// closeClient closes the client, unless it was provided with NewWithClient
+// and is owned by the caller. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) closeClient() {
+	if c.clientProvided || c.client == nil {
+		return
+	}
+
+	c.client.Close()
+	c.client = nil
+	c.gauges.setClient(nil)
+}
@@ -0,0 +1,135 @@
+// Command asadopt brings an existing Aerospike user under Vault management,
+// without recreating it, by registering it as a static role of the database
+// secrets engine. Vault rotates the password of a static role as soon as it is
+// created, through the plugin, so the previous password stops working right
+// away.
+//
+// The Vault address and token are taken from the usual VAULT_ADDR and
+// VAULT_TOKEN environment variables. The user must be listed in the
+// static_usernames of the plugin config, when it is set. When the plugin
+// config given with -config sets audit_namespace, the adoption is recorded in
+// the audit record of the user, like the users created by the plugin.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	aerospike "github.com/aerospike-community/vault-plugin-database-aerospike"
+	as "github.com/aerospike/aerospike-client-go/v5"
+	"github.com/hashicorp/vault/api"
+)
+
+func main() {
+	mount := flag.String("mount", "database", "path of the database secrets engine")
+	db := flag.String("db", "", "name of the database config in Vault")
+	username := flag.String("username", "", "Aerospike user to adopt")
+	role := flag.String("role", "", "name of the static role to create, the username by default")
+	rotationPeriod := flag.String("rotation-period", "24h", "rotation period of the static role")
+	configPath := flag.String("config", "", "path of a JSON file holding the plugin connection parameters, to check the user before adopting it and record its adoption")
+	flag.Parse()
+
+	if *db == "" || *username == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *role == "" {
+		*role = *username
+	}
+
+	if strings.HasPrefix(*username, aerospike.UsernamePrefix) {
+		log.Fatalf("%s looks like a user generated by Vault, which Vault already manages", *username)
+	}
+
+	var conf map[string]interface{}
+	var roles []string
+	if *configPath != "" {
+		var err error
+		if conf, err = readConfig(*configPath); err != nil {
+			log.Fatal(err)
+		}
+		if roles, err = userRoles(conf, *username); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s holds the roles: %s\n", *username, strings.Join(roles, ", "))
+	}
+
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rolePath := fmt.Sprintf("%s/static-roles/%s", strings.Trim(*mount, "/"), *role)
+	existing, err := client.Logical().Read(rolePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if existing != nil {
+		log.Fatalf("static role %s already exists", rolePath)
+	}
+
+	_, err = client.Logical().Write(rolePath, map[string]interface{}{
+		"db_name":         *db,
+		"username":        *username,
+		"rotation_period": *rotationPeriod,
+	})
+	if err != nil {
+		log.Fatalf("unable to create static role %s: %v", rolePath, err)
+	}
+
+	creds, err := client.Logical().Read(fmt.Sprintf("%s/static-creds/%s", strings.Trim(*mount, "/"), *role))
+	if err != nil || creds == nil {
+		log.Fatalf("static role %s was created, but its credentials could not be read: %v", rolePath, err)
+	}
+
+	fmt.Printf("%s is now managed by Vault as %s, its password was rotated at %v\n", *username, rolePath, creds.Data["last_vault_rotation"])
+
+	if conf != nil {
+		recorded, err := aerospike.RecordAdoption(context.Background(), conf, *username, *role, roles)
+		switch {
+		case err != nil:
+			log.Fatalf("unable to record the adoption of %s: %v", *username, err)
+		case recorded:
+			fmt.Printf("the adoption of %s was recorded in its audit record\n", *username)
+		}
+	}
+}
+
+// readConfig reads the plugin config at configPath.
+func readConfig(configPath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", configPath, err)
+	}
+	return conf, nil
+}
+
+// userRoles connects to the cluster described by the plugin config conf, and
+// returns the roles of username, failing if it does not exist.
+func userRoles(conf map[string]interface{}, username string) ([]string, error) {
+	client, err := aerospike.Connect(context.Background(), conf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect: %v", err)
+	}
+	defer client.Close()
+
+	user, err := client.QueryUser(as.NewAdminPolicy(), username)
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up %s: %v", username, err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%s does not exist", username)
+	}
+
+	return user.Roles, nil
+}
@@ -0,0 +1,75 @@
+// Command ascreds reads dynamic Aerospike credentials from Vault and prints
+// them in a form ready to be used by the Aerospike tools (asadm, aql, ...).
+//
+// The Vault address and token are taken from the usual VAULT_ADDR and
+// VAULT_TOKEN environment variables.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func main() {
+	path := flag.String("path", "", "Vault path of the credentials, e.g. database/creds/as-reader")
+	host := flag.String("host", "", "Aerospike host, using the same syntax as the tools")
+	format := flag.String("format", "args", "output format: args (command line arguments) or config (astools config file)")
+	flag.Parse()
+
+	if *path == "" || *host == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	secret, err := client.Logical().Read(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if secret == nil {
+		log.Fatalf("no credentials found at %s", *path)
+	}
+
+	username, _ := secret.Data["username"].(string)
+	password, _ := secret.Data["password"].(string)
+	if username == "" || password == "" {
+		log.Fatalf("%s did not return a username and password", *path)
+	}
+
+	switch *format {
+	case "args":
+		writeArgs(os.Stdout, *host, username, password)
+	case "config":
+		writeConfig(os.Stdout, *host, username, password)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
+
+// writeArgs writes the connection arguments accepted by both asadm and aql.
+func writeArgs(w io.Writer, host, username, password string) {
+	fmt.Fprintf(w, "-h %s -U %s -P%s\n", shellQuote(host), shellQuote(username), shellQuote(password))
+}
+
+// writeConfig writes an astools configuration file.
+func writeConfig(w io.Writer, host, username, password string) {
+	fmt.Fprintln(w, "[cluster]")
+	fmt.Fprintf(w, "host = %q\n", host)
+	fmt.Fprintf(w, "user = %q\n", username)
+	fmt.Fprintf(w, "password = %q\n", password)
+}
+
+// shellQuote quotes s so that it is interpreted literally by a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,125 @@
+// Command asreport lists the Aerospike users generated by Vault, with their
+// roles and age, as JSON or CSV evidence for access reviews.
+//
+// It reads the same connection parameters as the plugin from a JSON file, so
+// that the plugin config (or a copy holding password_file instead of the
+// password) can be reused as is.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	aerospike "github.com/aerospike-community/vault-plugin-database-aerospike"
+	as "github.com/aerospike/aerospike-client-go/v5"
+)
+
+// user is a line of the report.
+type user struct {
+	Username string     `json:"username"`
+	Roles    []string   `json:"roles"`
+	Created  *time.Time `json:"created,omitempty"`
+	Age      int64      `json:"age_seconds,omitempty"`
+}
+
+func main() {
+	configPath := flag.String("config", "", "path of a JSON file holding the plugin connection parameters")
+	format := flag.String("format", "json", "output format: json or csv")
+	flag.Parse()
+
+	if *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		log.Fatalf("unable to parse %s: %v", *configPath, err)
+	}
+
+	client, err := aerospike.Connect(context.Background(), conf)
+	if err != nil {
+		log.Fatalf("unable to connect: %v", err)
+	}
+	defer client.Close()
+
+	all, err := client.QueryUsers(as.NewAdminPolicy())
+	if err != nil {
+		log.Fatalf("unable to query users: %v", err)
+	}
+
+	now := time.Now()
+	var users []user
+	for _, u := range all {
+		if !strings.HasPrefix(u.User, aerospike.UsernamePrefix) {
+			continue
+		}
+		line := user{Username: u.User, Roles: u.Roles}
+		if created, ok := creationTime(u.User); ok {
+			line.Created = &created
+			line.Age = int64(now.Sub(created) / time.Second)
+		}
+		users = append(users, line)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+
+	if *format == "csv" {
+		writeCSV(users)
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(users); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// creationTime returns the creation time found at the end of a username
+// generated by Vault. It is missing when the username was truncated.
+func creationTime(username string) (time.Time, bool) {
+	i := strings.LastIndex(username, "-")
+	if i < 0 || len(username)-i-1 != 10 {
+		return time.Time{}, false
+	}
+
+	sec, err := strconv.ParseInt(username[i+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(sec, 0).UTC(), true
+}
+
+func writeCSV(users []user) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"username", "roles", "created", "age_seconds"})
+	for _, u := range users {
+		created, age := "", ""
+		if u.Created != nil {
+			created = u.Created.Format(time.RFC3339)
+			age = strconv.FormatInt(u.Age, 10)
+		}
+		w.Write([]string{u.Username, strings.Join(u.Roles, " "), created, age})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
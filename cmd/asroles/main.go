@@ -0,0 +1,106 @@
+// Command asroles prints the Aerospike roles with their privileges,
+// whitelists and quotas, to help writing creation statements.
+//
+// It reads the same connection parameters as the plugin from a JSON file, so
+// that the plugin config (or a copy holding password_file instead of the
+// password) can be reused as is.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	aerospike "github.com/aerospike-community/vault-plugin-database-aerospike"
+	as "github.com/aerospike/aerospike-client-go/v5"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path of a JSON file holding the plugin connection parameters")
+	jsonOutput := flag.Bool("json", false, "print the roles as JSON")
+	flag.Parse()
+
+	if *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		log.Fatalf("unable to parse %s: %v", *configPath, err)
+	}
+
+	client, err := aerospike.Connect(context.Background(), conf)
+	if err != nil {
+		log.Fatalf("unable to connect: %v", err)
+	}
+	defer client.Close()
+
+	roles, err := queryRoles(client)
+	if err != nil {
+		log.Fatalf("unable to query roles: %v", err)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(roles); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tPRIVILEGES\tWHITELIST\tREAD QUOTA\tWRITE QUOTA")
+	for _, r := range roles {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, privileges(r.Privileges), list(r.Whitelist), quota(r.ReadQuota), quota(r.WriteQuota))
+	}
+	w.Flush()
+}
+
+// queryRoles returns all roles. The client panics on privileges it does not
+// know, such as those introduced with Aerospike 6.
+func queryRoles(client *as.Client) (roles []*as.Role, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("a role holds a privilege unknown to the Aerospike client: %v", r)
+		}
+	}()
+
+	return client.QueryRoles(as.NewAdminPolicy())
+}
+
+// privileges formats privileges as expected_roles does.
+func privileges(privileges []as.Privilege) string {
+	result := make([]string, 0, len(privileges))
+	for _, p := range privileges {
+		result = append(result, aerospike.FormatPrivilege(p))
+	}
+	return list(result)
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
+
+func quota(q uint32) string {
+	if q == 0 {
+		return "-"
+	}
+	return fmt.Sprint(q)
+}
@@ -0,0 +1,174 @@
+// Command assoak runs the plugin against a cluster for a long time, creating,
+// verifying and revoking users in a loop, while tracking the goroutines, file
+// descriptors and memory of the process, so that leaks in the connection
+// handling are caught before they affect a Vault node.
+//
+// It reads the same connection parameters as the plugin from a JSON file, so
+// that the plugin config (or a copy holding password_file instead of the
+// password) can be reused as is. The plugin's user must be able to create and
+// drop users.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	aerospike "github.com/aerospike-community/vault-plugin-database-aerospike"
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+)
+
+// closeGracePeriod is how long the client's background goroutines are given
+// to stop after the plugin is closed.
+const closeGracePeriod = 5 * time.Second
+
+// maxGoroutineGrowth is the number of goroutines left after closing the
+// plugin, above the number before initializing it, that is reported as a
+// leak.
+const maxGoroutineGrowth = 2
+
+func main() {
+	configPath := flag.String("config", "", "path of a JSON file holding the plugin connection parameters")
+	duration := flag.Duration("duration", time.Hour, "how long to run")
+	interval := flag.Duration("interval", time.Second, "pause between cycles")
+	reportInterval := flag.Duration("report", time.Minute, "interval between resource reports")
+	roles := flag.String("roles", "read", "comma separated roles granted to the users")
+	flag.Parse()
+
+	if *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		log.Fatalf("unable to parse %s: %v", *configPath, err)
+	}
+	// The plugin checks that every user can log in
+	conf["verify_new_users"] = true
+
+	statement, err := json.Marshal(map[string][]string{"roles": strings.Split(*roles, ",")})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	before := measure()
+	log.Printf("before init: %s", before)
+
+	ctx := context.Background()
+	plugin, err := aerospike.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db := plugin.(dbplugin.Database)
+
+	if _, err := db.Init(ctx, conf, true); err != nil {
+		log.Fatalf("unable to initialize the plugin: %v", err)
+	}
+	log.Printf("after init: %s", measure())
+
+	var cycles, failures int
+	deadline := time.Now().Add(*duration)
+	nextReport := time.Now().Add(*reportInterval)
+
+	for time.Now().Before(deadline) {
+		if err := cycle(ctx, db, string(statement)); err != nil {
+			failures++
+			log.Printf("cycle %d failed: %v", cycles+1, err)
+		}
+		cycles++
+
+		if time.Now().After(nextReport) {
+			log.Printf("%d cycles, %d failures: %s", cycles, failures, measure())
+			nextReport = time.Now().Add(*reportInterval)
+		}
+
+		time.Sleep(*interval)
+	}
+
+	if err := db.Close(); err != nil {
+		log.Printf("unable to close the plugin: %v", err)
+	}
+	time.Sleep(closeGracePeriod)
+
+	after := measure()
+	log.Printf("%d cycles, %d failures, after close: %s", cycles, failures, after)
+
+	leaked := false
+	if after.goroutines > before.goroutines+maxGoroutineGrowth {
+		log.Printf("goroutine leak: %d before init, %d after close", before.goroutines, after.goroutines)
+		leaked = true
+	}
+	if before.fds >= 0 && after.fds > before.fds {
+		log.Printf("file descriptor leak: %d before init, %d after close", before.fds, after.fds)
+		leaked = true
+	}
+
+	if leaked || failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// cycle creates a user, which the plugin verifies by logging in as it, and
+// revokes it.
+func cycle(ctx context.Context, db dbplugin.Database, statement string) error {
+	username, _, err := db.CreateUser(ctx,
+		dbplugin.Statements{Creation: []string{statement}},
+		dbplugin.UsernameConfig{DisplayName: "soak", RoleName: "soak"},
+		time.Now().Add(time.Hour))
+	if err != nil {
+		return fmt.Errorf("unable to create user: %w", err)
+	}
+
+	if err := db.RevokeUser(ctx, dbplugin.Statements{}, username); err != nil {
+		return fmt.Errorf("unable to revoke %s: %w", username, err)
+	}
+
+	return nil
+}
+
+// resources are the resources used by the process at a point in time.
+type resources struct {
+	goroutines int
+	fds        int // -1 when unknown
+	heap       uint64
+}
+
+func (r resources) String() string {
+	fds := "unknown"
+	if r.fds >= 0 {
+		fds = fmt.Sprint(r.fds)
+	}
+	return fmt.Sprintf("goroutines=%d fds=%s heap=%dKiB", r.goroutines, fds, r.heap/1024)
+}
+
+func measure() resources {
+	runtime.GC()
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	r := resources{
+		goroutines: runtime.NumGoroutine(),
+		fds:        -1,
+		heap:       m.HeapAlloc,
+	}
+
+	// Open file descriptors are only known on Linux
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		r.fds = len(entries)
+	}
+
+	return r
+}
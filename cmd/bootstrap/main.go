@@ -0,0 +1,123 @@
+// Command bootstrap creates the Aerospike user the Vault plugin connects as,
+// with only the privileges the plugin needs, and prints the matching Vault
+// config command.
+//
+// The superuser password is read from the AEROSPIKE_SUPERUSER_PASSWORD
+// environment variable so that it does not end up in the shell history.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	aerospike "github.com/aerospike-community/vault-plugin-database-aerospike"
+	as "github.com/aerospike/aerospike-client-go/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
+)
+
+func main() {
+	host := flag.String("host", "", "Aerospike host, using the same syntax as the plugin host parameter")
+	superuser := flag.String("superuser", "admin", "existing user allowed to create users")
+	tlsCA := flag.String("tls-ca", "", "path of the PEM encoded CA used to validate the server certificates")
+	username := flag.String("username", "vault-admin", "name of the user to create for the plugin")
+	extraRoles := flag.String("extra-roles", "", "comma separated roles to grant in addition to "+aerospike.AdminRole)
+	mount := flag.String("config", "database/config/aerospike", "Vault path of the database config in the printed command")
+	allowedRoles := flag.String("allowed-roles", "", "comma separated Vault roles allowed to use the connection in the printed command")
+	flag.Parse()
+
+	if *host == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	superuserPassword := os.Getenv("AEROSPIKE_SUPERUSER_PASSWORD")
+	if superuserPassword == "" {
+		log.Fatal("AEROSPIKE_SUPERUSER_PASSWORD must be set")
+	}
+
+	roles := []string{aerospike.AdminRole}
+	for _, r := range strings.Split(*extraRoles, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+
+	ctx := context.Background()
+
+	client, err := aerospike.Connect(ctx, connConfig(*host, *tlsCA, *superuser, superuserPassword))
+	if err != nil {
+		log.Fatalf("unable to connect as %s: %v", *superuser, err)
+	}
+	defer client.Close()
+
+	password, err := credsutil.RandomAlphaNumeric(20, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := client.CreateUser(as.NewAdminPolicy(), *username, password, roles); err != nil {
+		log.Fatalf("unable to create %s: %v", *username, err)
+	}
+
+	if err := verify(ctx, connConfig(*host, *tlsCA, *username, password), *username); err != nil {
+		log.Fatalf("%s was created but could not be verified: %v", *username, err)
+	}
+
+	// Vault roles must be allowed explicitly, rather than suggesting "*"
+	if *allowedRoles == "" {
+		*allowedRoles = "<vault-role>"
+	}
+
+	args := []string{
+		"vault write " + *mount,
+		"plugin_name=aerospike-database-plugin",
+		fmt.Sprintf("allowed_roles=%q", *allowedRoles),
+		"host=" + *host,
+		"username=" + *username,
+		"password=" + password,
+	}
+	if *tlsCA != "" {
+		args = append(args, "tls_ca=@"+*tlsCA)
+	}
+	fmt.Println(strings.Join(args, " \\\n    "))
+}
+
+// connConfig returns a plugin configuration for the given credentials.
+func connConfig(host, tlsCA, username, password string) map[string]interface{} {
+	conf := map[string]interface{}{
+		"host":     host,
+		"username": username,
+		"password": password,
+	}
+	if tlsCA != "" {
+		conf["tls_ca_file"] = tlsCA
+	}
+	return conf
+}
+
+// verify logs in as the new user and checks that it was granted the admin
+// role.
+func verify(ctx context.Context, conf map[string]interface{}, username string) error {
+	client, err := aerospike.Connect(ctx, conf)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	user, err := client.QueryUser(as.NewAdminPolicy(), username)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range user.Roles {
+		if r == aerospike.AdminRole {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s does not have the %s role", username, aerospike.AdminRole)
+}
@@ -2,22 +2,73 @@ package aerospike
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aerospike/aerospike-client-go/v5"
-	"github.com/hashicorp/errwrap"
-	"github.com/hashicorp/vault/sdk/database/helper/connutil"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
 	"github.com/mitchellh/mapstructure"
 )
 
+// AdminRole is the only role the plugin's own user needs to manage users.
+const AdminRole = "user-admin"
+
+const (
+	defaultBootstrapUsername = "vault-admin"
+
+	defaultSlowOperationThreshold = 2 * time.Second
+)
+
+// Connection verification modes.
+const (
+	// verifyModeClient creates the client used for operations, which
+	// discovers and connects to every node of the cluster.
+	verifyModeClient = "client"
+	// verifyModeInfo logs into a single seed host and sends it an info
+	// command.
+	verifyModeInfo = "info"
+)
+
+// Connection strategies.
+const (
+	// connectLazy creates the client on first use, unless the connection is
+	// verified at Init.
+	connectLazy = "lazy"
+	// connectEager always creates the client at Init.
+	connectEager = "eager"
+)
+
+// minRecommendedNodes is the cluster size under which Init warns that the
+// cluster has no redundancy.
+const minRecommendedNodes = 2
+
+// secretDirEnv is the environment variable holding the directory that
+// password_file, tls_certificate_key_file and tls_ca_file must be in, so that
+// whoever can write the config cannot make the plugin read any file the
+// plugin process can read. Secrets cannot be read from files when it is not
+// set.
+const secretDirEnv = "AEROSPIKE_PLUGIN_SECRET_DIR"
+
+// envReference matches the "${VAR}" references expanded by expandEnv.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
 // aerospikeConnectionProducer implements ConnectionProducer and provides an
 // interface for databases to make connections.
 type aerospikeConnectionProducer struct {
+	ConfigSchemaVersion int `json:"config_schema_version" structs:"config_schema_version" mapstructure:"config_schema_version"`
+
 	Host string `json:"host" structs:"host" mapstructure:"host"`
 
 	Username string `json:"username" structs:"username" mapstructure:"username"`
@@ -26,73 +77,802 @@ type aerospikeConnectionProducer struct {
 	TLSCertificateKeyData []byte `json:"tls_certificate_key" structs:"-" mapstructure:"tls_certificate_key"`
 	TLSCAData             []byte `json:"tls_ca"              structs:"-" mapstructure:"tls_ca"`
 
+	PasswordFile          string `json:"password_file"            structs:"password_file"            mapstructure:"password_file"`
+	TLSCertificateKeyFile string `json:"tls_certificate_key_file" structs:"tls_certificate_key_file" mapstructure:"tls_certificate_key_file"`
+	TLSCAFile             string `json:"tls_ca_file"              structs:"tls_ca_file"              mapstructure:"tls_ca_file"`
+
+	TLSPKCS12         string `json:"tls_pkcs12"          structs:"-" mapstructure:"tls_pkcs12"`
+	TLSPKCS12Password string `json:"tls_pkcs12_password" structs:"-" mapstructure:"tls_pkcs12_password"`
+
+	TLSRequireOCSPStapling bool   `json:"tls_require_ocsp_stapling" structs:"tls_require_ocsp_stapling" mapstructure:"tls_require_ocsp_stapling"`
+	TLSCRL                 string `json:"tls_crl"                   structs:"tls_crl"                   mapstructure:"tls_crl"`
+
+	TLSUseSystemRoots bool `json:"tls_use_system_roots" structs:"tls_use_system_roots" mapstructure:"tls_use_system_roots"`
+
+	TLSDisableSessionTickets bool `json:"tls_disable_session_tickets" structs:"tls_disable_session_tickets" mapstructure:"tls_disable_session_tickets"`
+	TLSSessionCacheSize      int  `json:"tls_session_cache_size"      structs:"tls_session_cache_size"      mapstructure:"tls_session_cache_size"`
+
+	TLSPinnedSPKI []string `json:"tls_pinned_spki" structs:"tls_pinned_spki" mapstructure:"tls_pinned_spki"`
+
+	TLSExpiryThresholdDays int  `json:"tls_expiry_threshold_days" structs:"tls_expiry_threshold_days" mapstructure:"tls_expiry_threshold_days"`
+	TLSExpiryFail          bool `json:"tls_expiry_fail"           structs:"tls_expiry_fail"           mapstructure:"tls_expiry_fail"`
+
+	OrderedFailover bool `json:"ordered_failover" structs:"ordered_failover" mapstructure:"ordered_failover"`
+
+	AuthMode string `json:"auth_mode" structs:"auth_mode" mapstructure:"auth_mode"`
+
+	IPMapRaw interface{} `json:"ip_map" structs:"ip_map" mapstructure:"ip_map"`
+
+	ConnectionQueueSize         int   `json:"connection_queue_size"            structs:"connection_queue_size"            mapstructure:"connection_queue_size"`
+	LimitConnectionsToQueueSize *bool `json:"limit_connections_to_queue_size" structs:"limit_connections_to_queue_size" mapstructure:"limit_connections_to_queue_size"`
+
+	Stateless bool `json:"stateless" structs:"stateless" mapstructure:"stateless"`
+
+	ReadOnly    bool `json:"read_only"   structs:"read_only"   mapstructure:"read_only"`
+	Maintenance bool `json:"maintenance" structs:"maintenance" mapstructure:"maintenance"`
+
+	VerifyMode string `json:"verify_mode" structs:"verify_mode" mapstructure:"verify_mode"`
+
+	Connect string `json:"connect" structs:"connect" mapstructure:"connect"`
+
+	VerifyNewUsers  bool   `json:"verify_new_users"  structs:"verify_new_users"  mapstructure:"verify_new_users"`
+	VerifyNamespace string `json:"verify_namespace"  structs:"verify_namespace"  mapstructure:"verify_namespace"`
+	VerifyWrite     bool   `json:"verify_write"      structs:"verify_write"      mapstructure:"verify_write"`
+
+	VerifyStaticUsers bool        `json:"verify_static_users" structs:"verify_static_users" mapstructure:"verify_static_users"`
+	VerifyTimeoutRaw  interface{} `json:"verify_timeout"      structs:"verify_timeout"      mapstructure:"verify_timeout"`
+
+	LogFormat string `json:"log_format" structs:"log_format" mapstructure:"log_format"`
+
+	// MountLabel identifies the mount or database config in log lines and
+	// errors, since Vault does not tell plugins which one they serve.
+	MountLabel string `json:"mount_label" structs:"mount_label" mapstructure:"mount_label"`
+
+	// InsecureDebug disables the redaction of secrets in returned errors. It
+	// is only meant for troubleshooting in lab environments.
+	InsecureDebug bool `json:"insecure_debug" structs:"insecure_debug" mapstructure:"insecure_debug"`
+
+	CanaryIntervalRaw interface{} `json:"canary_interval" structs:"canary_interval" mapstructure:"canary_interval"`
+	CanaryRoles       []string    `json:"canary_roles"    structs:"canary_roles"    mapstructure:"canary_roles"`
+
+	JobSplayRaw interface{} `json:"job_splay" structs:"job_splay" mapstructure:"job_splay"`
+
+	JobWindows         []string `json:"job_windows"          structs:"job_windows"          mapstructure:"job_windows"`
+	JobWindowsTimezone string   `json:"job_windows_timezone" structs:"job_windows_timezone" mapstructure:"job_windows_timezone"`
+
+	RoleBundlesRaw interface{} `json:"role_bundles" structs:"role_bundles" mapstructure:"role_bundles"`
+
+	ExpectedRolesRaw      interface{} `json:"expected_roles"       structs:"expected_roles"       mapstructure:"expected_roles"`
+	DriftCheckIntervalRaw interface{} `json:"drift_check_interval" structs:"drift_check_interval" mapstructure:"drift_check_interval"`
+
+	WebhookURL         string      `json:"webhook_url"          structs:"webhook_url"          mapstructure:"webhook_url"`
+	WebhookAuthHeader  string      `json:"webhook_auth_header"  structs:"webhook_auth_header"  mapstructure:"webhook_auth_header"`
+	WebhookMaxAttempts int         `json:"webhook_max_attempts" structs:"webhook_max_attempts" mapstructure:"webhook_max_attempts"`
+	WebhookTimeoutRaw  interface{} `json:"webhook_timeout"      structs:"webhook_timeout"      mapstructure:"webhook_timeout"`
+
+	SummaryIntervalRaw interface{} `json:"summary_interval" structs:"summary_interval" mapstructure:"summary_interval"`
+
+	IdleDisconnectTimeoutRaw interface{} `json:"idle_disconnect_timeout" structs:"idle_disconnect_timeout" mapstructure:"idle_disconnect_timeout"`
+
+	NodeStatsIntervalRaw      interface{} `json:"node_stats_interval"      structs:"node_stats_interval"      mapstructure:"node_stats_interval"`
+	SlowOperationThresholdRaw interface{} `json:"slow_operation_threshold" structs:"slow_operation_threshold" mapstructure:"slow_operation_threshold"`
+
+	RoleCacheTTLRaw interface{} `json:"role_cache_ttl" structs:"role_cache_ttl" mapstructure:"role_cache_ttl"`
+
+	CreateTimeoutRaw         interface{} `json:"create_timeout"          structs:"create_timeout"          mapstructure:"create_timeout"`
+	DropTimeoutRaw           interface{} `json:"drop_timeout"            structs:"drop_timeout"            mapstructure:"drop_timeout"`
+	PasswordChangeTimeoutRaw interface{} `json:"password_change_timeout" structs:"password_change_timeout" mapstructure:"password_change_timeout"`
+
+	ProtectedUsers    []string `json:"protected_users" structs:"protected_users" mapstructure:"protected_users"`
+	ReservedUsernames []string `json:"reserved_usernames" structs:"reserved_usernames" mapstructure:"reserved_usernames"`
+
+	UsernameMaxLength    int `json:"username_max_length"    structs:"username_max_length"    mapstructure:"username_max_length"`
+	UsernameRandomLength int `json:"username_random_length" structs:"username_random_length" mapstructure:"username_random_length"`
+
+	StaticUsernames []string `json:"static_usernames" structs:"static_usernames" mapstructure:"static_usernames"`
+
+	RevocationRetry            bool   `json:"revocation_retry"              structs:"revocation_retry"              mapstructure:"revocation_retry"`
+	RevocationRetryMaxAttempts int    `json:"revocation_retry_max_attempts" structs:"revocation_retry_max_attempts" mapstructure:"revocation_retry_max_attempts"`
+	RevocationStateFile        string `json:"revocation_state_file"         structs:"revocation_state_file"         mapstructure:"revocation_state_file"`
+
+	AllowUnprefixedDrops bool `json:"allow_unprefixed_drops" structs:"allow_unprefixed_drops" mapstructure:"allow_unprefixed_drops"`
+
+	MaxDynamicUsers int `json:"max_dynamic_users" structs:"max_dynamic_users" mapstructure:"max_dynamic_users"`
+
+	AuditNamespace string `json:"audit_namespace" structs:"audit_namespace" mapstructure:"audit_namespace"`
+	AuditSet       string `json:"audit_set"       structs:"audit_set"       mapstructure:"audit_set"`
+
+	AllowedPrivileges []string `json:"allowed_privileges" structs:"allowed_privileges" mapstructure:"allowed_privileges"`
+
+	Bootstrap         bool   `json:"bootstrap"          structs:"bootstrap"          mapstructure:"bootstrap"`
+	BootstrapUsername string `json:"bootstrap_username" structs:"bootstrap_username" mapstructure:"bootstrap_username"`
+
+	RetryMaxAttempts  int         `json:"retry_max_attempts" structs:"retry_max_attempts" mapstructure:"retry_max_attempts"`
+	RetryBaseDelayRaw interface{} `json:"retry_base_delay"   structs:"retry_base_delay"   mapstructure:"retry_base_delay"`
+	RetryMaxDelayRaw  interface{} `json:"retry_max_delay"    structs:"retry_max_delay"    mapstructure:"retry_max_delay"`
+	RetryJitter       float64     `json:"retry_jitter"       structs:"retry_jitter"       mapstructure:"retry_jitter"`
+
 	Initialized  bool
 	RawConfig    map[string]interface{}
 	Type         string
 	hosts        []*aerospike.Host
 	clientPolicy *aerospike.ClientPolicy
-	client       *aerospike.Client
-	sync.Mutex
-}
+	client       Client
+	retry        retryPolicy
+	logger       hclog.Logger
+	jobStops     []chan struct{}
+	jobsRunning  sync.WaitGroup
+	roleCache    *roleCache
+	dynamicUsers userCount
 
-func (c *aerospikeConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
-	_, err := c.Init(ctx, conf, verifyConnection)
-	return err
+	// clientProvided is set when client was given to NewWithClient, in which
+	// case it is never replaced nor closed.
+	clientProvided bool
+
+	nodeStatsInterval      time.Duration
+	idleDisconnectTimeout  time.Duration
+	lastUsed               time.Time
+	pendingRevocations     map[string]*pendingRevocation
+	revocationStore        RevocationStore
+	customRevocationStore  RevocationStore
+	clientPolicyMutator    func(*aerospike.ClientPolicy)
+	ipMap                  map[string]string
+	authMode               aerospike.AuthMode
+	lockWaits              *waitHistogram
+	counters               *opCounters
+	gauges                 *gauges
+	summaryInterval        time.Duration
+	canaryInterval         time.Duration
+	expectedRoles          map[string][]string
+	roleBundles            map[string]roleBundle
+	roleBundlesApplied     bool
+	jobWindows             []timeWindow
+	jobSplay               time.Duration
+	jobWindowsLocation     *time.Location
+	driftCheckInterval     time.Duration
+	webhookTimeout         time.Duration
+	webhooks               *webhookQueue
+	crl                    *crlChecker
+	createTimeout          time.Duration
+	dropTimeout            time.Duration
+	passwordChangeTimeout  time.Duration
+	slowOperationThreshold time.Duration
+	verifyTimeout          time.Duration
+	sync.Mutex
 }
 
-// Initialize parses connection configuration.
-func (c *aerospikeConnectionProducer) Init(ctx context.Context, conf map[string]interface{}, verifyConnection bool) (map[string]interface{}, error) {
+// configure parses connection configuration, and returns the config to
+// store.
+func (c *aerospikeConnectionProducer) configure(ctx context.Context, conf map[string]interface{}, verifyConnection bool) (map[string]interface{}, error) {
 	c.Lock()
 	defer c.Unlock()
 
 	c.RawConfig = conf
+	c.stopJobs()
+
+	if err := c.parseConfig(conf); err != nil {
+		return nil, &kindError{kind: ErrInvalidConfig, err: err}
+	}
+
+	// Set initialized to true at this point since all fields are set,
+	// and the connection can be established at a later time.
+	c.Initialized = true
+	c.gauges.setInitialized()
+
+	if c.Bootstrap {
+		if err := c.bootstrap(ctx, conf); err != nil {
+			return nil, fmt.Errorf("error bootstrapping admin user: %w", err)
+		}
+	}
+
+	if verifyConnection {
+		if err := c.verifyConnection(ctx); err != nil {
+			return nil, fmt.Errorf("error verifying connection: %w", err)
+		}
+	}
+
+	c.roleBundlesApplied = false
+	if verifyConnection && c.VerifyMode != verifyModeInfo && !c.ReadOnly && len(c.roleBundles) > 0 {
+		client, err := c.Connection(ctx)
+		if err == nil {
+			err = c.applyRoleBundles(client.(Client), adminPolicy(ctx))
+		}
+		c.releaseConnection()
+		if err != nil {
+			return nil, fmt.Errorf("error applying role_bundles: %w", explainAdminError(err))
+		}
+	}
+
+	if c.Connect == connectEager && c.client == nil {
+		// Connection problems are only fatal when verifying the connection
+		if _, err := c.Connection(ctx); err != nil {
+			c.warn("unable to connect: %s", err)
+		}
+	}
+
+	if c.nodeStatsInterval > 0 {
+		c.startJob(c.nodeStatsInterval, c.logNodeStats)
+	}
+
+	if c.RevocationRetry {
+		c.loadPendingRevocations()
+		c.startJob(revocationRetryInterval, c.retryRevocations)
+	}
+
+	if c.canaryInterval > 0 {
+		c.startJob(c.canaryInterval, c.inJobWindows("canary", c.runCanary))
+	}
+
+	if len(c.expectedRoles) > 0 {
+		c.startJob(c.driftCheckInterval, c.inJobWindows("role_drift_check", c.checkRoleDrift))
+	}
+
+	if c.summaryInterval > 0 {
+		c.startJob(c.summaryInterval, c.logSummary)
+	}
+
+	if c.TLSCRL != "" {
+		c.startJob(crlRefreshInterval, c.refreshCRL)
+	}
+
+	if c.idleDisconnectTimeout > 0 {
+		c.startJob(c.idleDisconnectTimeout/2, c.disconnectIfIdle)
+	}
+
+	return conf, nil
+}
+
+// verifyConnection checks that the cluster can be reached and that the
+// plugin's user can manage users, as configured by verify_mode. It must be
+// called with the lock held.
+func (c *aerospikeConnectionProducer) verifyConnection(ctx context.Context) error {
+	if c.VerifyMode == verifyModeInfo {
+		return c.verifyInfo()
+	}
+
+	if _, err := c.Connection(ctx); err != nil {
+		return err
+	}
+	defer c.releaseConnection()
+
+	if !c.client.IsConnected() {
+		return &ConnectionError{Hosts: c.hostList(), Err: errors.New("not connected")}
+	}
+
+	if n := len(c.client.GetNodes()); n < minRecommendedNodes {
+		c.warn("cluster only has %d node(s), at least %d are recommended", n, minRecommendedNodes)
+	}
+
+	// The user of a provided client is unknown unless it is configured
+	if c.Username != "" {
+		if err := c.checkAdminPrivileges(c.client); err != nil {
+			return explainAdminError(err)
+		}
+	}
 
-	err := mapstructure.WeakDecode(conf, c)
+	return nil
+}
+
+// parseConfig decodes and validates conf, and prepares everything needed to
+// connect.
+func (c *aerospikeConnectionProducer) parseConfig(conf map[string]interface{}) error {
+	err := c.migrateConfig(conf)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if len(c.Host) == 0 {
-		return nil, fmt.Errorf("host cannot be empty")
+	err = c.applyAliases(conf)
+	if err != nil {
+		return err
 	}
 
-	c.hosts, err = c.getHosts()
+	err = mapstructure.WeakDecode(conf, c)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if len(c.Username) == 0 {
-		return nil, fmt.Errorf("username cannot be empty")
+	// Keep going after a problem so that all of them are reported at once
+	var errs *multierror.Error
+
+	switch c.VerifyMode {
+	case "", verifyModeClient, verifyModeInfo:
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid verify_mode %q, must be %s or %s", c.VerifyMode, verifyModeClient, verifyModeInfo))
 	}
 
-	if len(c.Password) == 0 {
-		return nil, fmt.Errorf("password cannot be empty")
+	if c.ReadOnly {
+		for name, set := range map[string]bool{
+			"bootstrap":        c.Bootstrap,
+			"canary_interval":  c.CanaryIntervalRaw != nil,
+			"revocation_retry": c.RevocationRetry,
+		} {
+			if set {
+				errs = multierror.Append(errs, fmt.Errorf("%s cannot be used with read_only, since it modifies the cluster", name))
+			}
+		}
 	}
 
-	c.clientPolicy = aerospike.NewClientPolicy()
-	c.clientPolicy.User = c.Username
-	c.clientPolicy.Password = c.Password
+	switch c.Connect {
+	case "", connectLazy:
+	case connectEager:
+		if c.Stateless {
+			errs = multierror.Append(errs, fmt.Errorf("connect=%s cannot be used with stateless", connectEager))
+		}
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid connect %q, must be %s or %s", c.Connect, connectLazy, connectEager))
+	}
+
+	switch c.LogFormat {
+	case "", "standard":
+		c.logger = newLogger(false)
+	case "json":
+		c.logger = newLogger(true)
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid log_format %q, must be standard or json", c.LogFormat))
+	}
+
+	if c.MountLabel != "" {
+		c.logger = c.logger.With("mount", c.MountLabel)
+	}
+
+	if c.InsecureDebug {
+		c.warn("insecure_debug is enabled, errors returned to Vault are not sanitized and may contain secrets")
+	}
+
+	for name, value := range map[string]*string{
+		"host":                     &c.Host,
+		"username":                 &c.Username,
+		"password_file":            &c.PasswordFile,
+		"tls_certificate_key_file": &c.TLSCertificateKeyFile,
+		"tls_ca_file":              &c.TLSCAFile,
+	} {
+		*value, err = expandEnv(*value)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid %s: %w", name, err))
+		}
+	}
+
+	// A provided client is already connected, so the connection parameters
+	// are optional
+	c.hosts = nil
+	if len(c.Host) == 0 && !c.clientProvided {
+		errs = multierror.Append(errs, fmt.Errorf("host cannot be empty"))
+	} else if len(c.Host) > 0 {
+		if c.hosts, err = c.getHosts(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
 
-	c.clientPolicy.TlsConfig, err = c.getTLSConfig()
+	if len(c.Username) == 0 && !c.clientProvided {
+		errs = multierror.Append(errs, fmt.Errorf("username cannot be empty"))
+	}
+
+	if c.Bootstrap && c.clientProvided {
+		errs = multierror.Append(errs, fmt.Errorf("bootstrap cannot be used with a provided client"))
+	}
+
+	c.TLSPinnedSPKI = splitList(c.TLSPinnedSPKI)
+	c.ProtectedUsers = splitList(c.ProtectedUsers)
+	c.ReservedUsernames = splitList(c.ReservedUsernames)
+	c.StaticUsernames = splitList(c.StaticUsernames)
+	if c.UsernameMaxLength != 0 && (c.UsernameMaxLength < minUsernameLength || c.UsernameMaxLength > maxUsernameLength) {
+		errs = multierror.Append(errs, fmt.Errorf("username_max_length must be between %d and %d", minUsernameLength, maxUsernameLength))
+	}
+	if err := c.checkUsernameRandomLength(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	for _, p := range c.ReservedUsernames {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid pattern %q in reserved_usernames: %w", p, err))
+		}
+	}
+	c.AllowedPrivileges = splitList(c.AllowedPrivileges)
+	for _, p := range c.AllowedPrivileges {
+		if !knownPrivileges[p] {
+			errs = multierror.Append(errs, fmt.Errorf("unknown privilege %q in allowed_privileges", p))
+		}
+	}
+
+	c.authMode, err = parseAuthMode(c.AuthMode)
 	if err != nil {
-		return nil, err
+		errs = multierror.Append(errs, err)
 	}
 
-	// Set initialized to true at this point since all fields are set,
-	// and the connection can be established at a later time.
-	c.Initialized = true
+	// PKI authentication uses the client certificate instead of a password
+	if err := c.loadSecretFiles(); err != nil {
+		errs = multierror.Append(errs, err)
+	} else if len(c.Password) == 0 && c.AuthMode != authModePKI && !c.clientProvided {
+		errs = multierror.Append(errs, fmt.Errorf("password cannot be empty"))
+	}
 
-	if verifyConnection {
-		if _, err := c.Connection(ctx); err != nil {
-			return nil, errwrap.Wrapf("error verifying connection: {{err}}", err)
+	if c.RevocationRetryMaxAttempts < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("revocation_retry_max_attempts cannot be negative"))
+	}
+	switch {
+	case c.customRevocationStore != nil:
+		c.revocationStore = c.customRevocationStore
+	case c.RevocationStateFile != "":
+		c.revocationStore = fileRevocationStore{path: c.RevocationStateFile}
+	default:
+		c.revocationStore = nil
+	}
+
+	if c.ConnectionQueueSize < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("connection_queue_size cannot be negative"))
+	}
+
+	c.ipMap, err = parseIPMap(c.IPMapRaw)
+	if err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("invalid ip_map: %w", err))
+	}
+
+	// The TLS checks need the client policy
+	if err := c.buildClientPolicy(); err != nil {
+		errs = multierror.Append(errs, err)
+	} else {
+		tlsConfig := c.clientPolicy.TlsConfig
+		switch {
+		case c.AuthMode == authModeExternal && tlsConfig == nil:
+			errs = multierror.Append(errs, fmt.Errorf("auth_mode=%s requires TLS", authModeExternal))
+		case c.AuthMode == authModePKI && (tlsConfig == nil || len(tlsConfig.Certificates) == 0):
+			errs = multierror.Append(errs, fmt.Errorf("auth_mode=%s requires a client certificate", authModePKI))
+		}
+		c.checkTLSNames()
+		if err := c.checkCertificates(); err != nil {
+			errs = multierror.Append(errs, err)
 		}
+	}
+
+	c.retry, err = c.getRetryPolicy()
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
 
-		if !c.client.IsConnected() {
-			return nil, fmt.Errorf("error verifying connection: not connected")
+	c.nodeStatsInterval = 0
+	if c.NodeStatsIntervalRaw != nil {
+		c.nodeStatsInterval, err = parseutil.ParseDurationSecond(c.NodeStatsIntervalRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid node_stats_interval: %w", err))
 		}
 	}
 
-	return conf, nil
+	c.CanaryRoles = splitList(c.CanaryRoles)
+	c.canaryInterval = 0
+	if c.CanaryIntervalRaw != nil {
+		c.canaryInterval, err = parseutil.ParseDurationSecond(c.CanaryIntervalRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid canary_interval: %w", err))
+		} else if c.canaryInterval > 0 && c.canaryInterval < time.Second {
+			errs = multierror.Append(errs, fmt.Errorf("canary_interval must be at least 1s"))
+		}
+	}
+
+	c.jobSplay = 0
+	if c.JobSplayRaw != nil {
+		c.jobSplay, err = parseutil.ParseDurationSecond(c.JobSplayRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid job_splay: %w", err))
+		}
+	}
+
+	// Windows hold commas, so lists given as a string are separated by
+	// semicolons instead
+	var jobWindows []string
+	for _, s := range c.JobWindows {
+		for _, e := range strings.Split(s, ";") {
+			if e = strings.TrimSpace(e); e != "" {
+				jobWindows = append(jobWindows, e)
+			}
+		}
+	}
+	c.JobWindows = jobWindows
+	c.jobWindows = nil
+	for _, s := range c.JobWindows {
+		w, err := parseTimeWindow(s)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid job_windows: %w", err))
+			continue
+		}
+		c.jobWindows = append(c.jobWindows, w)
+	}
+
+	c.jobWindowsLocation = time.UTC
+	if c.JobWindowsTimezone != "" {
+		c.jobWindowsLocation, err = time.LoadLocation(c.JobWindowsTimezone)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid job_windows_timezone: %w", err))
+		}
+	}
+
+	c.roleBundles = nil
+	if c.RoleBundlesRaw != nil {
+		c.roleBundles, err = parseRoleBundles(c.RoleBundlesRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid role_bundles: %w", err))
+		}
+	}
+
+	c.expectedRoles = nil
+	if c.ExpectedRolesRaw != nil {
+		c.expectedRoles, err = parseExpectedRoles(c.ExpectedRolesRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid expected_roles: %w", err))
+		}
+	}
+
+	c.driftCheckInterval = defaultDriftCheckInterval
+	if c.DriftCheckIntervalRaw != nil {
+		c.driftCheckInterval, err = parseutil.ParseDurationSecond(c.DriftCheckIntervalRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid drift_check_interval: %w", err))
+		} else if c.driftCheckInterval < time.Second {
+			errs = multierror.Append(errs, fmt.Errorf("drift_check_interval must be at least 1s"))
+		}
+	}
+
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = multierror.Append(errs, fmt.Errorf("webhook_url must be an http or https URL"))
+		}
+	}
+	if c.WebhookMaxAttempts < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("webhook_max_attempts cannot be negative"))
+	}
+	c.webhookTimeout = defaultWebhookTimeout
+	if c.WebhookTimeoutRaw != nil {
+		c.webhookTimeout, err = parseutil.ParseDurationSecond(c.WebhookTimeoutRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid webhook_timeout: %w", err))
+		}
+	}
+
+	c.summaryInterval = 0
+	if c.SummaryIntervalRaw != nil {
+		c.summaryInterval, err = parseutil.ParseDurationSecond(c.SummaryIntervalRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid summary_interval: %w", err))
+		} else if c.summaryInterval > 0 && c.summaryInterval < time.Second {
+			errs = multierror.Append(errs, fmt.Errorf("summary_interval must be at least 1s"))
+		}
+	}
+
+	c.idleDisconnectTimeout = 0
+	if c.IdleDisconnectTimeoutRaw != nil {
+		c.idleDisconnectTimeout, err = parseutil.ParseDurationSecond(c.IdleDisconnectTimeoutRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid idle_disconnect_timeout: %w", err))
+		} else if c.idleDisconnectTimeout > 0 && c.idleDisconnectTimeout < time.Second {
+			errs = multierror.Append(errs, fmt.Errorf("idle_disconnect_timeout must be at least 1s"))
+		}
+	}
+
+	var roleCacheTTL time.Duration
+	if c.RoleCacheTTLRaw != nil {
+		roleCacheTTL, err = parseutil.ParseDurationSecond(c.RoleCacheTTLRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid role_cache_ttl: %w", err))
+		}
+	}
+	c.roleCache = newRoleCache(roleCacheTTL)
+
+	if c.VerifyWrite && c.VerifyNamespace == "" {
+		errs = multierror.Append(errs, fmt.Errorf("verify_write requires verify_namespace"))
+	}
+
+	if c.MaxDynamicUsers < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("max_dynamic_users must not be negative"))
+	}
+	c.dynamicUsers = userCount{}
+
+	if c.AuditSet == "" {
+		c.AuditSet = defaultAuditSet
+	}
+	if c.AuditNamespace != "" && c.ReadOnly {
+		errs = multierror.Append(errs, fmt.Errorf("audit_namespace cannot be used with read_only, since it modifies the cluster"))
+	}
+
+	c.verifyTimeout = defaultVerifyTimeout
+	if c.VerifyTimeoutRaw != nil {
+		c.verifyTimeout, err = parseutil.ParseDurationSecond(c.VerifyTimeoutRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid verify_timeout: %w", err))
+		}
+	}
+
+	for name, t := range map[string]struct {
+		raw interface{}
+		d   *time.Duration
+	}{
+		"create_timeout":          {c.CreateTimeoutRaw, &c.createTimeout},
+		"drop_timeout":            {c.DropTimeoutRaw, &c.dropTimeout},
+		"password_change_timeout": {c.PasswordChangeTimeoutRaw, &c.passwordChangeTimeout},
+	} {
+		*t.d = 0
+		if t.raw != nil {
+			*t.d, err = parseutil.ParseDurationSecond(t.raw)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("invalid %s: %w", name, err))
+			}
+		}
+	}
+
+	c.slowOperationThreshold = defaultSlowOperationThreshold
+	if c.SlowOperationThresholdRaw != nil {
+		c.slowOperationThreshold, err = parseutil.ParseDurationSecond(c.SlowOperationThresholdRaw)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid slow_operation_threshold: %w", err))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// bootstrap uses the configured credentials, expected to be those of a
+// superuser, to create a dedicated admin user for the plugin, then switches to
+// it. conf is updated to hold the credentials of the new user, so that the
+// superuser password is never stored.
+func (c *aerospikeConnectionProducer) bootstrap(ctx context.Context, conf map[string]interface{}) error {
+	if c.PasswordFile != "" {
+		return fmt.Errorf("bootstrap cannot be used with password_file")
+	}
+
+	username := c.BootstrapUsername
+	if username == "" {
+		username = defaultBootstrapUsername
+	}
+
+	password, err := credsutil.RandomAlphaNumeric(20, true)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.Connection(ctx); err != nil {
+		return err
+	}
+
+	if err := c.client.CreateUser(aerospike.NewAdminPolicy(), username, password, []string{AdminRole}); err != nil {
+		return err
+	}
+
+	c.closeClient()
+
+	c.Username = username
+	c.Password = password
+	if err := c.buildClientPolicy(); err != nil {
+		return err
+	}
+
+	c.Bootstrap = false
+	conf["username"] = username
+	conf["password"] = password
+	delete(conf, "bootstrap")
+
+	return nil
+}
+
+// configAliases maps field names used by other Vault database plugins to the
+// name of the equivalent field of this plugin.
+var configAliases = map[string]string{
+	"connection_url": "host",
+	"hosts":          "host",
+	"tls_ca_data":    "tls_ca",
+}
+
+// applyAliases renames the aliased fields found in conf to their canonical
+// name, so that the stored configuration only uses canonical names.
+func (c *aerospikeConnectionProducer) applyAliases(conf map[string]interface{}) error {
+	for alias, name := range configAliases {
+		value, ok := conf[alias]
+		if !ok {
+			continue
+		}
+
+		if list, ok := value.([]interface{}); ok {
+			hosts := make([]string, 0, len(list))
+			for _, h := range list {
+				hosts = append(hosts, fmt.Sprint(h))
+			}
+			value = strings.Join(hosts, ",")
+		}
+
+		if _, ok := conf[name]; ok {
+			return fmt.Errorf("%s and its alias %s cannot both be set", name, alias)
+		}
+
+		conf[name] = value
+		delete(conf, alias)
+		c.warn("%s is an alias, use %s instead", alias, name)
+	}
+
+	return nil
+}
+
+// expandEnv resolves references to environment variables of the plugin
+// process in s. A value of the form "env://VAR" is replaced as a whole by the
+// value of VAR, otherwise "${VAR}" references are expanded. Other uses of "$",
+// such as "$VAR", are kept as is.
+func expandEnv(s string) (string, error) {
+	if strings.HasPrefix(s, "env://") {
+		name := strings.TrimPrefix(s, "env://")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+
+	var err error
+	expanded := envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+
+	return expanded, err
+}
+
+// splitList splits the comma separated elements of list, so that list config
+// fields can be given either as a list or as a comma separated string.
+func splitList(list []string) []string {
+	var result []string
+	for _, e := range list {
+		for _, v := range strings.Split(e, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// parseIPMap parses the ip_map parameter, given either as an object or as a
+// comma separated list of <internal>=<external> pairs.
+func parseIPMap(raw interface{}) (map[string]string, error) {
+	ipMap := map[string]string{}
+
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		for internal, external := range v {
+			s, ok := external.(string)
+			if !ok {
+				return nil, fmt.Errorf("address for %q must be a string", internal)
+			}
+			ipMap[internal] = s
+		}
+	case string:
+		for _, pair := range splitList([]string{v}) {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+				return nil, fmt.Errorf("%q is not an <internal>=<external> pair", pair)
+			}
+			ipMap[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	default:
+		return nil, fmt.Errorf("must be an object or a string")
+	}
+
+	return ipMap, nil
+}
+
+// warn logs a non-fatal configuration problem found by Init. Version 4 of the
+// database plugin interface has no way to return it to Vault.
+func (c *aerospikeConnectionProducer) warn(format string, args ...interface{}) {
+	c.logger.Warn(fmt.Sprintf(format, args...))
+}
+
+// labelError prefixes err with MountLabel, when set, so that errors logged by
+// Vault can be told apart when several mounts use the plugin.
+func (c *aerospikeConnectionProducer) labelError(err error) error {
+	if err == nil || c.MountLabel == "" {
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", c.MountLabel, err)
 }
 
 // Connection creates or returns an existing a database connection. If the session fails
@@ -101,56 +881,318 @@ func (c *aerospikeConnectionProducer) Init(ctx context.Context, conf map[string]
 // responsibility.
 func (c *aerospikeConnectionProducer) Connection(ctx context.Context) (interface{}, error) {
 	if !c.Initialized {
-		return nil, connutil.ErrNotInitialized
+		return nil, ErrNotInitialized
 	}
 
+	c.lastUsed = time.Now()
+
 	// If we already have a session, test it and return
 	if c.client != nil {
 		if c.client.IsConnected() {
 			return c.client, nil
 		}
+		if c.clientProvided {
+			return nil, &ConnectionError{Hosts: "the provided client", Err: errors.New("not connected")}
+		}
 		// If the ping was unsuccessful, close it and ignore errors as we'll be
 		// reestablishing anyways
 		c.client.Close()
 	}
 
-	var err error
-	c.client, err = aerospike.NewClientWithPolicyAndHost(c.clientPolicy, c.hosts...)
+	// Pick up secrets that may have been replaced on disk since the last
+	// connection
+	if c.hasSecretFiles() {
+		if err := c.loadSecretFiles(); err != nil {
+			return nil, err
+		}
+		if err := c.buildClientPolicy(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := injectFault("connect"); err != nil {
+		return nil, &ConnectionError{Hosts: c.hostList(), Err: err}
+	}
+
+	client, err := c.newClient()
 	if err != nil {
-		return nil, err
+		c.client = nil
+		c.gauges.setClient(nil)
+		return nil, &ConnectionError{Hosts: c.hostList(), Err: err}
 	}
+	c.client = client
+	c.gauges.setClient(client)
+	c.counters.connected()
 	return c.client, nil
 }
 
-// Close attempts to close the connection.
-func (c *aerospikeConnectionProducer) Close() error {
-	c.Lock()
-	defer c.Unlock()
+// buildClientPolicy creates the client policy used for new connections from
+// the credentials and TLS settings, and lets the function given to
+// WithClientPolicy change it.
+func (c *aerospikeConnectionProducer) buildClientPolicy() error {
+	tlsConfig, err := c.getTLSConfig()
+	if err != nil {
+		return err
+	}
 
-	if c.client != nil {
-		c.client.Close()
+	c.clientPolicy = aerospike.NewClientPolicy()
+	c.clientPolicy.User = c.Username
+	c.clientPolicy.Password = c.Password
+	c.clientPolicy.TlsConfig = tlsConfig
+	c.clientPolicy.IpMap = c.ipMap
+	c.clientPolicy.AuthMode = c.authMode
+
+	switch {
+	case c.ConnectionQueueSize > 0:
+		c.clientPolicy.ConnectionQueueSize = c.ConnectionQueueSize
+	case c.Stateless:
+		// The client only lives for a single operation, which never needs
+		// more than one connection per node
+		c.clientPolicy.ConnectionQueueSize = 1
+	}
+	if c.LimitConnectionsToQueueSize != nil {
+		c.clientPolicy.LimitConnectionsToQueueSize = *c.LimitConnectionsToQueueSize
 	}
 
-	c.client = nil
+	if c.clientPolicyMutator != nil {
+		c.clientPolicyMutator(c.clientPolicy)
+	}
+	c.gauges.setPoolSize(c.clientPolicy.ConnectionQueueSize)
+
+	return nil
+}
+
+// hasSecretFiles reports whether any secret is read from a file.
+func (c *aerospikeConnectionProducer) hasSecretFiles() bool {
+	return c.PasswordFile != "" || c.TLSCertificateKeyFile != "" || c.TLSCAFile != ""
+}
+
+// loadSecretFiles reads the secrets configured as files, replacing the
+// values of the corresponding fields.
+func (c *aerospikeConnectionProducer) loadSecretFiles() error {
+	if c.PasswordFile != "" {
+		if _, ok := c.RawConfig["password"]; ok {
+			return fmt.Errorf("password and password_file cannot both be set")
+		}
+
+		data, err := readSecretFile(c.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("unable to read password_file: %w", err)
+		}
+		c.Password = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if c.TLSCertificateKeyFile != "" {
+		if _, ok := c.RawConfig["tls_certificate_key"]; ok {
+			return fmt.Errorf("tls_certificate_key and tls_certificate_key_file cannot both be set")
+		}
+
+		data, err := readSecretFile(c.TLSCertificateKeyFile)
+		if err != nil {
+			return fmt.Errorf("unable to read tls_certificate_key_file: %w", err)
+		}
+		c.TLSCertificateKeyData = data
+	}
+
+	if c.TLSCAFile != "" {
+		if _, ok := c.RawConfig["tls_ca"]; ok {
+			return fmt.Errorf("tls_ca and tls_ca_file cannot both be set")
+		}
+
+		data, err := readSecretFile(c.TLSCAFile)
+		if err != nil {
+			return fmt.Errorf("unable to read tls_ca_file: %w", err)
+		}
+		c.TLSCAData = data
+	}
+
+	return nil
+}
+
+// readSecretFile reads the file at path, which must be in the directory named
+// by secretDirEnv. Relative paths are relative to that directory. Symbolic
+// links are resolved before checking the path, so that they cannot lead out
+// of the directory.
+func readSecretFile(path string) ([]byte, error) {
+	dir := os.Getenv(secretDirEnv)
+	if dir == "" {
+		return nil, fmt.Errorf("%s must be set in the environment of the plugin process to read secrets from files", secretDirEnv)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is not in %s (%s)", path, dir, secretDirEnv)
+	}
+
+	return os.ReadFile(resolved)
+}
+
+// verifyInfo connects and logs into the first reachable seed host and sends
+// it an info command, without creating a full client.
+func (c *aerospikeConnectionProducer) verifyInfo() error {
+	err := c.detectAuthMode(func(policy *aerospike.ClientPolicy) error {
+		var err error
+		for _, host := range c.hosts {
+			if err = requestInfo(policy, host); err == nil {
+				return nil
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return &ConnectionError{Hosts: c.hostList(), Err: err}
+	}
+
+	return nil
+}
+
+// requestInfo connects and logs into host using policy, then sends it an info
+// command.
+func requestInfo(policy *aerospike.ClientPolicy, host *aerospike.Host) error {
+	conn, err := aerospike.NewConnection(policy, tlsHost(policy, host))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetTimeout(time.Now().Add(policy.Timeout), policy.Timeout); err != nil {
+		return err
+	}
+
+	if err := conn.Login(policy); err != nil {
+		return err
+	}
+
+	_, err = conn.RequestInfo("node")
+	return err
+}
+
+// tlsHost returns host with the TLS name the client defaults to when TLS is
+// enabled, the cluster name or else the host name, which NewConnection leaves
+// empty and the handshake then fails. host is not modified.
+func tlsHost(policy *aerospike.ClientPolicy, host *aerospike.Host) *aerospike.Host {
+	if policy.TlsConfig == nil || policy.TlsConfig.InsecureSkipVerify || host.TLSName != "" {
+		return host
+	}
+
+	h := *host
+	if policy.ClusterName != "" {
+		h.TLSName = policy.ClusterName
+	} else {
+		h.TLSName = host.Name
+	}
+	return &h
+}
+
+// newClient creates a new client seeded with the configured hosts. When
+// OrderedFailover is set, the hosts are tried one at a time in the order they
+// were configured and the first one that connects is used. The
+// authentication mode is detected as described by detectAuthMode.
+func (c *aerospikeConnectionProducer) newClient() (client *aerospike.Client, err error) {
+	err = c.detectAuthMode(func(policy *aerospike.ClientPolicy) error {
+		client, err = newClientWithPolicy(policy, c.hosts, c.OrderedFailover)
+		return err
+	})
+	return client, err
+}
+
+func newClientWithPolicy(policy *aerospike.ClientPolicy, hosts []*aerospike.Host, orderedFailover bool) (*aerospike.Client, error) {
+	if !orderedFailover {
+		return aerospike.NewClientWithPolicyAndHost(policy, hosts...)
+	}
+
+	var err error
+	for _, host := range hosts {
+		var client *aerospike.Client
+		client, err = aerospike.NewClientWithPolicyAndHost(policy, host)
+		if err == nil {
+			return client, nil
+		}
+	}
+
+	return nil, err
+}
+
+// releaseConnection closes the client when Stateless is set, so that no
+// client is kept between operations. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) releaseConnection() {
+	if !c.Stateless {
+		return
+	}
+
+	c.closeClient()
+}
+
+// disconnectIfIdle closes the client if it was not used for
+// idleDisconnectTimeout. The next operation reconnects. It must be called
+// with the lock held.
+func (c *aerospikeConnectionProducer) disconnectIfIdle() {
+	if c.client == nil || c.clientProvided || time.Since(c.lastUsed) < c.idleDisconnectTimeout {
+		return
+	}
+
+	c.logger.Debug("closing idle connection", "idle", time.Since(c.lastUsed))
+	c.closeClient()
+}
+
+// Close attempts to close the connection, and drops the webhook events not
+// delivered yet. It returns once the background jobs have exited.
+func (c *aerospikeConnectionProducer) Close() error {
+	c.Lock()
+	c.stopJobs()
+	c.stopWebhooks()
+	c.closeClient()
+	c.Unlock()
+
+	c.jobsRunning.Wait()
 
 	return nil
 }
 
 func (c *aerospikeConnectionProducer) secretValues() map[string]interface{} {
-	return map[string]interface{}{
+	if c.InsecureDebug {
+		return nil
+	}
+
+	values := map[string]interface{}{
 		c.Password: "[password]",
 	}
+	if c.TLSPKCS12Password != "" {
+		values[c.TLSPKCS12Password] = "[tls_pkcs12_password]"
+	}
+	if c.WebhookAuthHeader != "" {
+		values[c.WebhookAuthHeader] = "[webhook_auth_header]"
+	}
+	return values
 }
 
 // getHosts parses the Host string in a format compatible with the aerospike CLI tools
 func (c *aerospikeConnectionProducer) getHosts() ([]*aerospike.Host, error) {
 	hosts := []*aerospike.Host{}
+	var errs *multierror.Error
 
 	for i, h := range strings.Split(c.Host, ",") {
 		components := strings.Split(h, ":")
 
 		if len(components) > 3 {
-			return nil, fmt.Errorf("too many components for host #%d", i+1)
+			errs = multierror.Append(errs, fmt.Errorf("too many components for host #%d", i+1))
+			continue
 		}
 
 		name := components[0]
@@ -159,7 +1201,8 @@ func (c *aerospikeConnectionProducer) getHosts() ([]*aerospike.Host, error) {
 			var err error
 			port, err = strconv.Atoi(components[len(components)-1])
 			if err != nil {
-				return nil, fmt.Errorf("invalid port number for host #%d: %w", i+1, err)
+				errs = multierror.Append(errs, fmt.Errorf("invalid port number for host #%d: %w", i+1, err))
+				continue
 			}
 		}
 
@@ -172,33 +1215,73 @@ func (c *aerospikeConnectionProducer) getHosts() ([]*aerospike.Host, error) {
 		hosts = append(hosts, host)
 	}
 
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
 	return hosts, nil
 }
 
-// getTLSConfig parses the TLSCAData and TLSCertificateKeyData byte slices and
-// builds a tls.Config.
-func (c *aerospikeConnectionProducer) getTLSConfig() (*tls.Config, error) {
-	if len(c.TLSCAData) == 0 {
-		return nil, nil
+// getRetryPolicy validates the retry settings and fills in defaults for the
+// ones that were not set.
+func (c *aerospikeConnectionProducer) getRetryPolicy() (retryPolicy, error) {
+	p := retryPolicy{
+		maxAttempts: defaultRetryMaxAttempts,
+		baseDelay:   defaultRetryBaseDelay,
+		maxDelay:    defaultRetryMaxDelay,
+		jitter:      c.RetryJitter,
 	}
 
-	tlsConfig := &tls.Config{
-		RootCAs: x509.NewCertPool(),
+	if c.RetryMaxAttempts < 0 {
+		return p, fmt.Errorf("retry_max_attempts cannot be negative")
+	}
+	if c.RetryMaxAttempts > 0 {
+		p.maxAttempts = c.RetryMaxAttempts
 	}
 
-	ok := tlsConfig.RootCAs.AppendCertsFromPEM(c.TLSCAData)
-	if !ok {
-		return nil, fmt.Errorf("failed to append CA to client policy")
+	if c.RetryBaseDelayRaw != nil {
+		d, err := parseutil.ParseDurationSecond(c.RetryBaseDelayRaw)
+		if err != nil {
+			return p, fmt.Errorf("invalid retry_base_delay: %w", err)
+		}
+		p.baseDelay = d
 	}
 
-	if len(c.TLSCertificateKeyData) > 0 {
-		certificate, err := tls.X509KeyPair(c.TLSCertificateKeyData, c.TLSCertificateKeyData)
+	if c.RetryMaxDelayRaw != nil {
+		d, err := parseutil.ParseDurationSecond(c.RetryMaxDelayRaw)
 		if err != nil {
-			return nil, fmt.Errorf("unable to load tls_certificate_key_data: %w", err)
+			return p, fmt.Errorf("invalid retry_max_delay: %w", err)
 		}
+		p.maxDelay = d
+	}
+
+	if p.baseDelay <= 0 || p.maxDelay < p.baseDelay {
+		return p, fmt.Errorf("retry_base_delay must be positive and not greater than retry_max_delay")
+	}
+
+	if p.jitter < 0 || p.jitter > 1 {
+		return p, fmt.Errorf("retry_jitter must be between 0 and 1")
+	}
+
+	return p, nil
+}
+
+// hostList describes the parsed hosts, using the same syntax as the host
+// field, for use in error messages.
+func (c *aerospikeConnectionProducer) hostList() string {
+	hosts := make([]string, 0, len(c.hosts))
+	for _, h := range c.hosts {
+		if h.TLSName != "" {
+			hosts = append(hosts, fmt.Sprintf("%s:%s:%d", h.Name, h.TLSName, h.Port))
+		} else {
+			hosts = append(hosts, fmt.Sprintf("%s:%d", h.Name, h.Port))
+		}
+	}
 
-		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	tls := "without TLS"
+	if c.clientPolicy != nil && c.clientPolicy.TlsConfig != nil {
+		tls = "with TLS"
 	}
 
-	return tlsConfig, nil
+	return fmt.Sprintf("%s (%s)", strings.Join(hosts, ","), tls)
 }
@@ -0,0 +1,297 @@
+package aerospike
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/hashicorp/go-hclog"
+)
+
+func newTestProducer() *aerospikeConnectionProducer {
+	return &aerospikeConnectionProducer{logger: hclog.NewNullLogger()}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    map[string]interface{}
+		wantErr string
+		check   func(t *testing.T, c *aerospikeConnectionProducer)
+	}{
+		{
+			name: "minimal",
+			conf: map[string]interface{}{"host": "localhost:3000", "username": "admin", "password": "secret"},
+			check: func(t *testing.T, c *aerospikeConnectionProducer) {
+				if len(c.hosts) != 1 || c.hosts[0].Name != "localhost" || c.hosts[0].Port != 3000 {
+					t.Errorf("unexpected hosts %v", c.hosts)
+				}
+			},
+		},
+		{
+			name: "tls name",
+			conf: map[string]interface{}{"host": "10.0.0.1:aerospike.example.com:4333", "username": "admin", "password": "secret"},
+			check: func(t *testing.T, c *aerospikeConnectionProducer) {
+				if len(c.hosts) != 1 || c.hosts[0].TLSName != "aerospike.example.com" || c.hosts[0].Port != 4333 {
+					t.Errorf("unexpected hosts %v", c.hosts)
+				}
+			},
+		},
+		{
+			name:    "missing host",
+			conf:    map[string]interface{}{"username": "admin", "password": "secret"},
+			wantErr: "host cannot be empty",
+		},
+		{
+			name:    "missing username and password",
+			conf:    map[string]interface{}{"host": "localhost"},
+			wantErr: "username cannot be empty",
+		},
+		{
+			name:    "invalid port",
+			conf:    map[string]interface{}{"host": "localhost:port", "username": "admin", "password": "secret"},
+			wantErr: "invalid port number for host #1",
+		},
+		{
+			name:    "invalid verify_mode",
+			conf:    map[string]interface{}{"host": "localhost", "username": "admin", "password": "secret", "verify_mode": "ping"},
+			wantErr: `invalid verify_mode "ping"`,
+		},
+		{
+			name:    "username_max_length too short",
+			conf:    map[string]interface{}{"host": "localhost", "username": "admin", "password": "secret", "username_max_length": 20},
+			wantErr: "username_max_length must be between 23 and 63",
+		},
+		{
+			name:    "username_random_length too long",
+			conf:    map[string]interface{}{"host": "localhost", "username": "admin", "password": "secret", "username_max_length": 30, "username_random_length": 20},
+			wantErr: "username_random_length must be between 10 and 17",
+		},
+		{
+			name:    "Aerospike 6 privilege",
+			conf:    map[string]interface{}{"host": "localhost", "username": "admin", "password": "secret", "allowed_privileges": "read,truncate"},
+			wantErr: `unknown privilege "truncate" in allowed_privileges`,
+		},
+		{
+			name:    "read_only with revocation_retry",
+			conf:    map[string]interface{}{"host": "localhost", "username": "admin", "password": "secret", "read_only": true, "revocation_retry": true},
+			wantErr: "revocation_retry cannot be used with read_only",
+		},
+		{
+			name:    "alias and canonical name",
+			conf:    map[string]interface{}{"host": "localhost", "connection_url": "localhost", "username": "admin", "password": "secret"},
+			wantErr: "host and its alias connection_url cannot both be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestProducer()
+			err := c.parseConfig(tt.conf)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+}
+
+func TestApplyAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "no alias",
+			conf: map[string]interface{}{"host": "a"},
+			want: map[string]interface{}{"host": "a"},
+		},
+		{
+			name: "connection_url",
+			conf: map[string]interface{}{"connection_url": "a:3000"},
+			want: map[string]interface{}{"host": "a:3000"},
+		},
+		{
+			name: "hosts list",
+			conf: map[string]interface{}{"hosts": []interface{}{"a:3000", "b:3000"}},
+			want: map[string]interface{}{"host": "a:3000,b:3000"},
+		},
+		{
+			name: "tls_ca_data",
+			conf: map[string]interface{}{"tls_ca_data": "pem"},
+			want: map[string]interface{}{"tls_ca": "pem"},
+		},
+		{
+			name:    "both set",
+			conf:    map[string]interface{}{"hosts": "a", "host": "b"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestProducer().applyAliases(tt.conf)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(tt.conf) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, tt.conf)
+			}
+			for k, v := range tt.want {
+				if tt.conf[k] != v {
+					t.Errorf("expected %s=%v, got %v", k, v, tt.conf[k])
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfigMultipleErrors(t *testing.T) {
+	err := newTestProducer().parseConfig(map[string]interface{}{"verify_mode": "ping"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"host cannot be empty", "username cannot be empty", "invalid verify_mode"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestTLSHost(t *testing.T) {
+	tests := []struct {
+		name        string
+		tlsConfig   *tls.Config
+		clusterName string
+		tlsName     string
+		want        string
+	}{
+		{name: "without TLS"},
+		{name: "host name", tlsConfig: &tls.Config{}, want: "10.0.0.1"},
+		{name: "cluster name", tlsConfig: &tls.Config{}, clusterName: "prod", want: "prod"},
+		{name: "configured TLS name", tlsConfig: &tls.Config{}, clusterName: "prod", tlsName: "aerospike.example.com", want: "aerospike.example.com"},
+		{name: "verification disabled", tlsConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := aerospike.NewClientPolicy()
+			policy.TlsConfig = tt.tlsConfig
+			policy.ClusterName = tt.clusterName
+			host := &aerospike.Host{Name: "10.0.0.1", TLSName: tt.tlsName, Port: 4333}
+
+			got := tlsHost(policy, host)
+			if got.TLSName != tt.want || got.Name != host.Name || got.Port != host.Port {
+				t.Errorf("expected TLS name %q, got %v", tt.want, got)
+			}
+			if host.TLSName != tt.tlsName {
+				t.Errorf("host was modified: %v", host)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("AEROSPIKE_TEST_HOST", "10.0.0.1")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "no reference", value: "localhost:3000", want: "localhost:3000"},
+		{name: "whole value", value: "env://AEROSPIKE_TEST_HOST", want: "10.0.0.1"},
+		{name: "inline", value: "${AEROSPIKE_TEST_HOST}:3000", want: "10.0.0.1:3000"},
+		{name: "bare reference kept", value: "pa$AEROSPIKE_TEST_HOST", want: "pa$AEROSPIKE_TEST_HOST"},
+		{name: "unset whole value", value: "env://AEROSPIKE_TEST_UNSET", wantErr: true},
+		{name: "unset inline", value: "${AEROSPIKE_TEST_UNSET}:3000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEnv(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	for _, path := range []string{filepath.Join(dir, "password"), filepath.Join(outside, "password")} {
+		if err := os.WriteFile(path, []byte("secret"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "password"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		secretDir string
+		path      string
+		wantErr   bool
+	}{
+		{name: "absolute path", secretDir: dir, path: filepath.Join(dir, "password")},
+		{name: "relative path", secretDir: dir, path: "password"},
+		{name: "directory not set", path: filepath.Join(dir, "password"), wantErr: true},
+		{name: "outside the directory", secretDir: dir, path: filepath.Join(outside, "password"), wantErr: true},
+		{name: "parent reference", secretDir: dir, path: filepath.Join(dir, "..", filepath.Base(outside), "password"), wantErr: true},
+		{name: "symbolic link out of the directory", secretDir: dir, path: filepath.Join(dir, "link"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(secretDirEnv, tt.secretDir)
+
+			data, err := readSecretFile(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != "secret" {
+				t.Errorf("unexpected content %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package aerospike
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// waitBuckets are the upper bounds of the buckets of a waitHistogram.
+var waitBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// waitHistogram records how long operations waited to acquire a resource.
+// A nil waitHistogram records nothing.
+type waitHistogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	total  time.Duration
+	max    time.Duration
+}
+
+func newWaitHistogram() *waitHistogram {
+	return &waitHistogram{counts: make([]uint64, len(waitBuckets)+1)}
+}
+
+func (h *waitHistogram) observe(d time.Duration) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(waitBuckets) && d > waitBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.total += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// snapshot returns the histogram as a JSON-encodable value. Bucket keys are
+// the upper bounds of the buckets, the last one being "+Inf".
+func (h *waitHistogram) snapshot() interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make(map[string]uint64, len(h.counts))
+	for i, n := range h.counts {
+		key := "+Inf"
+		if i < len(waitBuckets) {
+			key = waitBuckets[i].String()
+		}
+		buckets[key] = n
+	}
+
+	return map[string]interface{}{
+		"count":   h.count,
+		"total":   h.total.String(),
+		"max":     h.max.String(),
+		"buckets": buckets,
+	}
+}
+
+// ServeHTTP writes the histogram as JSON.
+func (h *waitHistogram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.snapshot())
+}
+
+// lockTimed acquires the lock and records how long it waited for it.
+func (c *aerospikeConnectionProducer) lockTimed() {
+	start := time.Now()
+	done := c.gauges.waitLock()
+	c.Lock()
+	done()
+	c.lockWaits.observe(time.Since(start))
+}
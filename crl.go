@@ -0,0 +1,163 @@
+package aerospike
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// crlRefreshInterval is how often the CRL is checked for being past its next
+// update time, in which case it is loaded again.
+const crlRefreshInterval = time.Minute
+
+// crlChecker checks server certificates against the CRL given by TLSCRL.
+// Checks only use the loaded CRL, so that handshakes never wait for it to be
+// downloaded: refresh loads it again once past its next update time, and is
+// run by a background job.
+type crlChecker struct {
+	location string
+	logger   hclog.Logger
+
+	mu      sync.Mutex
+	crl     *pkix.CertificateList
+	revoked map[string]bool
+}
+
+func newCRLChecker(location string, logger hclog.Logger) (*crlChecker, error) {
+	crl, revoked, err := loadRevoked(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &crlChecker{
+		location: location,
+		logger:   logger,
+		crl:      crl,
+		revoked:  revoked,
+	}, nil
+}
+
+// current returns the CRL and its revoked serial numbers.
+func (ch *crlChecker) current() (*pkix.CertificateList, map[string]bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.crl, ch.revoked
+}
+
+// refresh loads the CRL again if it is past its next update time. The
+// previous CRL is kept when it cannot be loaded. Checks keep using the
+// previous CRL while the new one is loaded.
+func (ch *crlChecker) refresh() {
+	if crl, _ := ch.current(); !crl.HasExpired(time.Now()) {
+		return
+	}
+
+	crl, revoked, err := loadRevoked(ch.location)
+	if err != nil {
+		ch.logger.Warn("unable to reload tls_crl past its next update time", "location", ch.location, "error", err)
+		return
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.crl, ch.revoked = crl, revoked
+}
+
+// refreshCRL loads the CRL given by TLSCRL again when it is past its next
+// update time. The lock is released while loading it, since downloading it
+// may take a while. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) refreshCRL() {
+	crl := c.crl
+	if crl == nil {
+		return
+	}
+
+	c.Unlock()
+	defer c.lockTimed()
+	crl.refresh()
+}
+
+// loadRevoked loads the CRL found at location, and returns it along with the
+// serial numbers it revokes.
+func loadRevoked(location string) (*pkix.CertificateList, map[string]bool, error) {
+	crl, err := loadCRL(location)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revoked := make(map[string]bool, len(crl.TBSCertList.RevokedCertificates))
+	for _, r := range crl.TBSCertList.RevokedCertificates {
+		revoked[r.SerialNumber.String()] = true
+	}
+
+	return crl, revoked, nil
+}
+
+// check returns an error if the CRL was not issued and signed by the issuer of
+// the leaf of chain, a verified chain, or if it revokes the leaf. Serial
+// numbers are only unique per issuer, hence the issuer check.
+func (ch *crlChecker) check(chain []*x509.Certificate) error {
+	crl, revoked := ch.current()
+	leaf := chain[0]
+
+	var leafIssuer pkix.RDNSequence
+	if _, err := asn1.Unmarshal(leaf.RawIssuer, &leafIssuer); err != nil {
+		return fmt.Errorf("unable to parse the issuer of server certificate %q: %w", leaf.Subject, err)
+	}
+	if crl.TBSCertList.Issuer.String() != leafIssuer.String() {
+		return fmt.Errorf("tls_crl is issued by %q, not by %q, the issuer of server certificate %q", crl.TBSCertList.Issuer, leafIssuer, leaf.Subject)
+	}
+
+	issuer := leaf
+	if len(chain) > 1 {
+		issuer = chain[1]
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return fmt.Errorf("invalid signature of tls_crl: %w", err)
+	}
+
+	if revoked[leaf.SerialNumber.String()] {
+		return fmt.Errorf("server certificate %q is revoked by tls_crl", leaf.Subject)
+	}
+
+	return nil
+}
+
+// loadCRL reads a PEM or DER encoded CRL from a file or an HTTP(S) URL.
+// x509.ParseRevocationList requires Go 1.19, so the deprecated x509.ParseCRL
+// is used instead, along with an explicit check of the signature.
+func loadCRL(location string) (*pkix.CertificateList, error) {
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		var resp *http.Response
+		resp, err = client.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		data, err = io.ReadAll(resp.Body)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCRL(data)
+}
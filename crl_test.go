@@ -0,0 +1,75 @@
+package aerospike
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// writeTestCRL writes to path a CRL issued by ca, revoking revoked and valid
+// until nextUpdate.
+func writeTestCRL(t *testing.T, path string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, nextUpdate time.Time, revoked ...*x509.Certificate) {
+	t.Helper()
+
+	var entries []pkix.RevokedCertificate
+	for _, cert := range revoked {
+		entries = append(entries, pkix.RevokedCertificate{SerialNumber: cert.SerialNumber, RevocationTime: time.Now()})
+	}
+
+	der, err := ca.CreateCRL(rand.Reader, caKey, entries, time.Now().Add(-time.Hour), nextUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, der, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCRLCheckerRefresh(t *testing.T) {
+	ca, caKey := testCertificate(t, "test-ca", nil, nil)
+	leaf, _ := testCertificate(t, "aerospike", ca, caKey)
+	chain := []*x509.Certificate{leaf, ca}
+
+	tests := []struct {
+		name        string
+		nextUpdate  time.Duration
+		wantRevoked bool
+	}{
+		{name: "current CRL is kept", nextUpdate: time.Hour},
+		{name: "stale CRL is replaced", nextUpdate: -time.Minute, wantRevoked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "crl.der")
+			writeTestCRL(t, path, ca, caKey, time.Now().Add(tt.nextUpdate))
+
+			ch, err := newCRLChecker(path, hclog.NewNullLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Checks only use the loaded CRL
+			writeTestCRL(t, path, ca, caKey, time.Now().Add(time.Hour), leaf)
+			if err := ch.check(chain); err != nil {
+				t.Fatalf("unexpected error before refreshing: %v", err)
+			}
+
+			ch.refresh()
+			err = ch.check(chain)
+			if tt.wantRevoked && err == nil {
+				t.Error("expected the leaf to be revoked by the refreshed CRL")
+			}
+			if !tt.wantRevoked && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package aerospike
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+)
+
+// This file adapts the version 4 database plugin interface to the plugin's
+// operations, which do not depend on the interface. Supporting another
+// version of the interface only requires another set of adapters.
+
+var _ dbplugin.Database = &Aerospike{}
+
+// Type returns the TypeName for this backend
+func (a *Aerospike) Type() (string, error) {
+	return aerospikeTypeName, nil
+}
+
+// Init parses connection configuration, and returns the config to store.
+func (a *Aerospike) Init(ctx context.Context, conf map[string]interface{}, verifyConnection bool) (map[string]interface{}, error) {
+	conf, err := a.configure(ctx, conf, verifyConnection)
+	return conf, a.labelError(err)
+}
+
+// Initialize is the deprecated form of Init, kept for older Vault versions.
+func (a *Aerospike) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
+	_, err := a.configure(ctx, conf, verifyConnection)
+	return a.labelError(err)
+}
+
+// CreateUser creates a user with a generated username and password, as
+// instructed by the first creation statement.
+func (a *Aerospike) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+	statements = dbutil.StatementCompatibilityHelper(statements)
+	username, password, err = a.createUser(ctx, statements.Creation, usernameConfig.DisplayName, usernameConfig.RoleName)
+	return username, password, a.labelError(err)
+}
+
+// SetCredentials sets the password of an existing static user. Unlike
+// CreateUser, it uses the username given instead of generating one.
+func (a *Aerospike) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username, password string, err error) {
+	if err := a.setPassword(ctx, statements.Rotation, staticUser.Username, staticUser.Password); err != nil {
+		return "", "", a.labelError(err)
+	}
+
+	return staticUser.Username, staticUser.Password, nil
+}
+
+// RenewUser is not supported on Aerospike, so this is a no-op.
+func (a *Aerospike) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
+	// NOOP
+	return nil
+}
+
+// RevokeUser drops the specified user.
+func (a *Aerospike) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+	return a.labelError(a.dropUser(ctx, statements.Revocation, username))
+}
+
+// RotateRootCredentials rotates the initial root database credentials. The new
+// root password will only be known by Vault.
+func (a *Aerospike) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	config, err := a.rotateRoot(ctx)
+	return config, a.labelError(err)
+}
@@ -0,0 +1,46 @@
+package aerospike
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// debugAddrEnv is the environment variable holding the address of the debug
+// listener. The listener is only started when it is set.
+const debugAddrEnv = "AEROSPIKE_PLUGIN_DEBUG_ADDR"
+
+// startDebugListener starts an HTTP listener serving the pprof handlers, the
+// lock wait histogram and the expvar variables if debugAddrEnv is set.
+func startDebugListener(logger hclog.Logger, lockWaits *waitHistogram, counters *opCounters, gauges *gauges) {
+	addr := os.Getenv(debugAddrEnv)
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/lock-wait", lockWaits)
+
+	expvar.Publish("aerospike_lock_wait", expvar.Func(lockWaits.snapshot))
+	expvar.Publish("aerospike_operations", expvar.Func(counters.snapshot))
+	expvar.Publish("aerospike_pending_revocations", expvar.Func(gauges.snapshotPendingRevocations))
+	expvar.Publish("aerospike_open_connections", expvar.Func(gauges.openConnections))
+	expvar.Publish("aerospike_lock_queue", expvar.Func(gauges.snapshotLockQueue))
+	expvar.Publish("aerospike_connection_pool_size", expvar.Func(gauges.snapshotPoolSize))
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		logger.Warn("starting debug listener", "address", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Error("debug listener stopped", "error", err)
+		}
+	}()
+}
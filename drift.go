@@ -0,0 +1,122 @@
+package aerospike
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/mitchellh/mapstructure"
+)
+
+const defaultDriftCheckInterval = time.Hour
+
+// errRoleDrift is recorded in the operation counters when a role does not
+// grant the expected privileges.
+var errRoleDrift = errors.New("role privileges drifted")
+
+// parseExpectedRoles parses the expected_roles parameter, given either as an
+// object or as a JSON string, mapping role names to the privileges they are
+// expected to grant. Each privilege is written <code>[:<namespace>[.<set>]],
+// and lists may also be given as comma separated strings.
+func parseExpectedRoles(raw interface{}) (map[string][]string, error) {
+	if s, ok := raw.(string); ok {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, fmt.Errorf("must be an object or a JSON string: %w", err)
+		}
+		raw = m
+	}
+
+	var roles map[string][]string
+	if err := mapstructure.WeakDecode(raw, &roles); err != nil {
+		return nil, err
+	}
+
+	for name, privileges := range roles {
+		privileges = splitList(privileges)
+		for _, p := range privileges {
+			code := strings.SplitN(p, ":", 2)[0]
+			if !knownPrivileges[code] {
+				return nil, fmt.Errorf("unknown privilege %q for role %q", code, name)
+			}
+		}
+		roles[name] = privileges
+	}
+
+	return roles, nil
+}
+
+// FormatPrivilege returns p as <code>[:<namespace>[.<set>]], the format used
+// by expected_roles.
+func FormatPrivilege(p aerospike.Privilege) string {
+	s := string(p.Code)
+	if p.Namespace != "" {
+		s += ":" + p.Namespace
+		if p.SetName != "" {
+			s += "." + p.SetName
+		}
+	}
+	return s
+}
+
+// checkRoleDrift compares the privileges granted by the roles listed in
+// expected_roles with the expected ones, and logs a warning for every role
+// that drifted, so that out-of-band changes are noticed. It must be called
+// with the lock held.
+func (c *aerospikeConnectionProducer) checkRoleDrift() {
+	defer c.releaseConnection()
+
+	client, err := c.Connection(context.Background())
+	if err != nil {
+		c.logger.Error("unable to check role drift", "error", err)
+		return
+	}
+
+	names := make([]string, 0, len(c.expectedRoles))
+	for name := range c.expectedRoles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		role, err := c.queryRole(client.(Client), name)
+		if err != nil {
+			c.counters.record("role_drift_check", err)
+			c.logger.Error("unable to look up role to check drift", "role", name, "error", err)
+			if errors.Is(err, ErrUnknownPrivilege) {
+				return
+			}
+			continue
+		}
+
+		actual := make(map[string]bool, len(role.Privileges))
+		for _, p := range role.Privileges {
+			actual[FormatPrivilege(p)] = true
+		}
+
+		var missing, unexpected []string
+		for _, p := range c.expectedRoles[name] {
+			if !actual[p] {
+				missing = append(missing, p)
+			}
+			delete(actual, p)
+		}
+		for p := range actual {
+			unexpected = append(unexpected, p)
+		}
+		sort.Strings(unexpected)
+
+		if len(missing) == 0 && len(unexpected) == 0 {
+			c.counters.record("role_drift_check", nil)
+			continue
+		}
+
+		c.counters.record("role_drift_check", errRoleDrift)
+		c.logger.Warn("role privileges drifted", "role", name, "missing", missing, "unexpected", unexpected)
+	}
+}
@@ -0,0 +1,121 @@
+package aerospike
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+	"github.com/hashicorp/vault/sdk/database/helper/connutil"
+)
+
+var (
+	// ErrNotInitialized is returned when an operation is attempted before the
+	// database config was written. It wraps connutil.ErrNotInitialized.
+	ErrNotInitialized = fmt.Errorf("%w: write the database config (vault write <mount>/config/<name> plugin_name=... host=...) and make sure its connection verification succeeds before requesting credentials", connutil.ErrNotInitialized)
+
+	// ErrInvalidConfig matches the errors caused by an invalid database config.
+	ErrInvalidConfig = errors.New("invalid database config")
+
+	// ErrInvalidStatement matches the errors caused by an invalid statement.
+	ErrInvalidStatement = errors.New("invalid statement")
+
+	// ErrPrivilegeNotAllowed is returned when a creation statement grants a
+	// privilege that is not part of allowed_privileges.
+	ErrPrivilegeNotAllowed = errors.New("privilege not allowed")
+
+	// ErrProtectedUser is returned when an operation targets a user that
+	// Vault must not drop.
+	ErrProtectedUser = errors.New("user is protected")
+
+	// ErrReservedUsername is returned when an operation targets a username
+	// that matches reserved_usernames.
+	ErrReservedUsername = errors.New("username is reserved")
+
+	// ErrStaticUserNotFound is returned when the password of a static user
+	// that does not exist in the cluster is set.
+	ErrStaticUserNotFound = errors.New("static user does not exist")
+
+	// ErrStaticUserNotAllowed is returned when the password of a static user
+	// that is not listed in static_usernames is set.
+	ErrStaticUserNotAllowed = errors.New("static user not allowed")
+
+	// ErrReadOnly is returned when a mutating operation is attempted while
+	// read_only is set.
+	ErrReadOnly = errors.New("plugin is in read-only mode")
+
+	// ErrMaintenance is returned when a user is created while maintenance is
+	// set.
+	ErrMaintenance = errors.New("plugin is in maintenance mode, no new users are created")
+
+	// ErrTooManyUsers is returned when a user is created while
+	// max_dynamic_users users generated by Vault already exist.
+	ErrTooManyUsers = errors.New("too many users generated by Vault")
+
+	// ErrAdminRestricted matches the errors returned when the cluster does not
+	// let the plugin manage users, as is common with managed Aerospike
+	// offerings.
+	ErrAdminRestricted = errors.New("user administration is restricted on this cluster")
+
+	// ErrUnknownPrivilege is returned when a role holds a privilege that the
+	// Aerospike client cannot decode, such as the ones introduced with
+	// Aerospike 6. The client is closed then, so nothing else may be done
+	// with it.
+	ErrUnknownPrivilege = errors.New("role holds a privilege unknown to the Aerospike client")
+)
+
+// ConnectionError is returned when the plugin cannot connect to the cluster.
+type ConnectionError struct {
+	// Hosts describes the hosts the plugin tried to connect to.
+	Hosts string
+	Err   error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("unable to connect to %s: %v", e.Hosts, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// kindError classifies err as being of a kind, such as ErrInvalidConfig, so
+// that errors.Is matches both the kind and the errors wrapped by err.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// explainAdminError classifies the errors returned by the cluster when it
+// does not let the plugin manage users as ErrAdminRestricted, with a hint on
+// the likely cause. Quotas not being enabled is explained with how to enable
+// them. Other errors are returned as is.
+func explainAdminError(err error) error {
+	var aerr aerospike.Error
+	if !errors.As(err, &aerr) {
+		return err
+	}
+
+	switch {
+	case aerr.Matches(types.SECURITY_NOT_ENABLED, types.SECURITY_NOT_SUPPORTED):
+		return &kindError{kind: ErrAdminRestricted, err: fmt.Errorf("security is not enabled on the cluster, or users are managed by the provider of a managed Aerospike offering: %w", err)}
+	case aerr.Matches(types.ROLE_VIOLATION, types.ALWAYS_FORBIDDEN):
+		return &kindError{kind: ErrAdminRestricted, err: fmt.Errorf("the plugin's user is not allowed to do this; make sure it holds the user-admin role, or, on a managed Aerospike offering, that the provider lets it manage users: %w", err)}
+	case aerr.Matches(types.QUOTAS_NOT_ENABLED):
+		return fmt.Errorf("quotas are not enabled on the cluster; set enable-quotas to true in the security context of the Aerospike configuration: %w", err)
+	default:
+		return err
+	}
+}
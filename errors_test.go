@@ -0,0 +1,40 @@
+package aerospike
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+)
+
+func TestExplainAdminError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		restricted bool
+		hint       string
+	}{
+		{name: "not an Aerospike error", err: errors.New("boom")},
+		{name: "security not enabled", err: &aerospike.AerospikeError{ResultCode: types.SECURITY_NOT_ENABLED}, restricted: true, hint: "security is not enabled"},
+		{name: "role violation", err: &aerospike.AerospikeError{ResultCode: types.ROLE_VIOLATION}, restricted: true, hint: "user-admin role"},
+		{name: "quotas not enabled", err: &aerospike.AerospikeError{ResultCode: types.QUOTAS_NOT_ENABLED}, hint: "enable-quotas"},
+		{name: "other error", err: &aerospike.AerospikeError{ResultCode: types.TIMEOUT}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := explainAdminError(tt.err)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v to wrap %v", err, tt.err)
+			}
+			if errors.Is(err, ErrAdminRestricted) != tt.restricted {
+				t.Errorf("expected ErrAdminRestricted to be %t, got %v", tt.restricted, err)
+			}
+			if tt.hint != "" && !strings.Contains(err.Error(), tt.hint) {
+				t.Errorf("expected %q in %v", tt.hint, err)
+			}
+		})
+	}
+}
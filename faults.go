@@ -0,0 +1,9 @@
+//go:build !chaos
+// +build !chaos
+
+package aerospike
+
+// injectFault is a no-op unless the plugin is built with the chaos build tag.
+func injectFault(point string) error {
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build chaos
+// +build chaos
+
+package aerospike
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// faultsEnv is the environment variable describing the faults to inject. It
+// holds a comma separated list of <point>=<action> pairs, where action is
+// fail (every call fails), fail:<n> (the next n calls fail) or
+// delay:<duration> (every call is delayed).
+const faultsEnv = "AEROSPIKE_PLUGIN_FAULTS"
+
+type fault struct {
+	// remaining is the number of calls left to fail, -1 meaning all of them
+	remaining int
+	delay     time.Duration
+}
+
+var (
+	faultsOnce sync.Once
+	faultsMu   sync.Mutex
+	faults     map[string]*fault
+)
+
+func loadFaults() {
+	faults = make(map[string]*fault)
+
+	for _, pair := range splitList([]string{os.Getenv(faultsEnv)}) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			panic(fmt.Sprintf("%s: %q is not a <point>=<action> pair", faultsEnv, pair))
+		}
+
+		action := strings.SplitN(kv[1], ":", 2)
+		f := &fault{}
+		switch {
+		case action[0] == "fail" && len(action) == 1:
+			f.remaining = -1
+		case action[0] == "fail":
+			n, err := strconv.Atoi(action[1])
+			if err != nil {
+				panic(fmt.Sprintf("%s: invalid count in %q: %v", faultsEnv, pair, err))
+			}
+			f.remaining = n
+		case action[0] == "delay" && len(action) == 2:
+			d, err := time.ParseDuration(action[1])
+			if err != nil {
+				panic(fmt.Sprintf("%s: invalid delay in %q: %v", faultsEnv, pair, err))
+			}
+			f.delay = d
+		default:
+			panic(fmt.Sprintf("%s: unknown action in %q", faultsEnv, pair))
+		}
+
+		faults[kv[0]] = f
+	}
+}
+
+// injectFault applies the fault configured for point, if any. Injected
+// failures are client timeouts, which the retry policy treats as transient.
+func injectFault(point string) error {
+	faultsOnce.Do(loadFaults)
+
+	faultsMu.Lock()
+	f, ok := faults[point]
+	if !ok {
+		faultsMu.Unlock()
+		return nil
+	}
+
+	delay := f.delay
+	fail := f.remaining != 0
+	if f.remaining > 0 {
+		f.remaining--
+	}
+	faultsMu.Unlock()
+
+	time.Sleep(delay)
+
+	if fail && delay == 0 {
+		return fmt.Errorf("injected fault at %s: %w", point, aerospike.ErrTimeout)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package aerospike
+
+import (
+	"sync/atomic"
+)
+
+// gauges hold point-in-time values of the producer published on the debug
+// listener and reported by the health service. They are updated with the lock
+// held, and read without it so that inspecting a plugin stuck on the lock
+// still works. A nil gauges records nothing.
+type gauges struct {
+	initialized        int32
+	pendingRevocations int64
+	lockQueue          int64
+	poolSize           int64
+	client             atomic.Value // liveClient
+}
+
+// liveClient wraps the current client, which may be nil, since atomic.Value
+// cannot hold a nil interface.
+type liveClient struct {
+	Client
+}
+
+func newGauges() *gauges {
+	g := &gauges{}
+	g.client.Store(liveClient{})
+	return g
+}
+
+// setClient records the current client, nil when disconnected.
+func (g *gauges) setClient(client Client) {
+	if g == nil {
+		return
+	}
+	g.client.Store(liveClient{client})
+}
+
+// currentClient returns the client last recorded by setClient.
+func (g *gauges) currentClient() Client {
+	return g.client.Load().(liveClient).Client
+}
+
+// setInitialized records that a config was successfully parsed.
+func (g *gauges) setInitialized() {
+	if g == nil {
+		return
+	}
+	atomic.StoreInt32(&g.initialized, 1)
+}
+
+func (g *gauges) isInitialized() bool {
+	return atomic.LoadInt32(&g.initialized) == 1
+}
+
+func (g *gauges) setPendingRevocations(n int) {
+	if g == nil {
+		return
+	}
+	atomic.StoreInt64(&g.pendingRevocations, int64(n))
+}
+
+func (g *gauges) setPoolSize(n int) {
+	if g == nil {
+		return
+	}
+	atomic.StoreInt64(&g.poolSize, int64(n))
+}
+
+// waitLock counts an operation waiting for the lock, until the returned
+// function is called.
+func (g *gauges) waitLock() func() {
+	if g == nil {
+		return func() {}
+	}
+	atomic.AddInt64(&g.lockQueue, 1)
+	return func() { atomic.AddInt64(&g.lockQueue, -1) }
+}
+
+// openConnections returns the number of connections the client has open to
+// the cluster, 0 when disconnected.
+func (g *gauges) openConnections() interface{} {
+	client := g.currentClient()
+	if client == nil {
+		return 0
+	}
+
+	stats, err := client.Stats()
+	if err != nil {
+		return 0
+	}
+	return stats["open-connections"]
+}
+
+func (g *gauges) snapshotPendingRevocations() interface{} {
+	return atomic.LoadInt64(&g.pendingRevocations)
+}
+
+func (g *gauges) snapshotLockQueue() interface{} {
+	return atomic.LoadInt64(&g.lockQueue)
+}
+
+func (g *gauges) snapshotPoolSize() interface{} {
+	return atomic.LoadInt64(&g.poolSize)
+}
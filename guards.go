@@ -0,0 +1,99 @@
+package aerospike
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+)
+
+// defaultReservedUsernames are the usernames Vault never manages, in addition
+// to the patterns in reserved_usernames.
+var defaultReservedUsernames = []string{"admin", "superuser"}
+
+// checkWritable returns ErrReadOnly if read_only is set.
+func (c *aerospikeConnectionProducer) checkWritable() error {
+	if c.ReadOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// checkCreatable returns ErrMaintenance if maintenance is set.
+func (c *aerospikeConnectionProducer) checkCreatable() error {
+	if c.Maintenance {
+		return ErrMaintenance
+	}
+	return nil
+}
+
+// checkDroppable returns an error if username is the plugin's own user or one
+// of the protected users, or, unless AllowUnprefixedDrops is set, if it was
+// not generated by Vault.
+func (c *aerospikeConnectionProducer) checkDroppable(username string) error {
+	if !c.AllowUnprefixedDrops && !strings.HasPrefix(username, UsernamePrefix) {
+		return fmt.Errorf("%w: %s does not start with %q; set allow_unprefixed_drops to drop it", ErrProtectedUser, username, UsernamePrefix)
+	}
+
+	if username == c.Username {
+		return fmt.Errorf("%w: %s is the user the plugin connects as", ErrProtectedUser, username)
+	}
+
+	for _, u := range c.ProtectedUsers {
+		if username == u {
+			return fmt.Errorf("%w: %s is listed in protected_users", ErrProtectedUser, username)
+		}
+	}
+
+	return nil
+}
+
+// checkReserved returns an error if username is one of the default reserved
+// usernames or matches a pattern of reserved_usernames. Patterns use the
+// syntax of path.Match and the comparison is case-insensitive.
+func (c *aerospikeConnectionProducer) checkReserved(username string) error {
+	name := strings.ToLower(username)
+
+	for _, u := range defaultReservedUsernames {
+		if name == u {
+			return fmt.Errorf("%w: %s", ErrReservedUsername, username)
+		}
+	}
+
+	for _, p := range c.ReservedUsernames {
+		// Patterns are validated when the config is parsed
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return fmt.Errorf("%w: %s matches %q", ErrReservedUsername, username, p)
+		}
+	}
+
+	return nil
+}
+
+// checkStaticAllowed returns an error if static_usernames is set and does not
+// list username.
+func (c *aerospikeConnectionProducer) checkStaticAllowed(username string) error {
+	if len(c.StaticUsernames) == 0 {
+		return nil
+	}
+
+	for _, u := range c.StaticUsernames {
+		if username == u {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not listed in static_usernames", ErrStaticUserNotAllowed, username)
+}
+
+// checkUserExists returns ErrStaticUserNotFound if username does not exist,
+// since the raw error of the cluster does not make it obvious.
+func checkUserExists(client Client, policy *aerospike.AdminPolicy, username string) error {
+	user, err := client.QueryUser(policy, username)
+	if (err == nil && user == nil) || (err != nil && err.Matches(types.INVALID_USER)) {
+		return fmt.Errorf("%w: %s must be created in Aerospike before Vault can manage it", ErrStaticUserNotFound, username)
+	}
+	return err
+}
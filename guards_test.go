@@ -0,0 +1,40 @@
+package aerospike
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDroppable(t *testing.T) {
+	tests := []struct {
+		name            string
+		username        string
+		allowUnprefixed bool
+		wantErr         bool
+	}{
+		{name: "generated user", username: "v-token-reader-abc-1602523665"},
+		{name: "unprefixed user", username: "app", wantErr: true},
+		{name: "unprefixed user allowed", username: "app", allowUnprefixed: true},
+		{name: "plugin user", username: "v-admin", wantErr: true},
+		{name: "plugin user with unprefixed drops", username: "admin", allowUnprefixed: true, wantErr: true},
+		{name: "protected user", username: "v-keep", wantErr: true},
+		{name: "protected user with unprefixed drops", username: "ops", allowUnprefixed: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestProducer()
+			c.Username = "v-admin"
+			c.ProtectedUsers = []string{"v-keep", "ops", "admin"}
+			c.AllowUnprefixedDrops = tt.allowUnprefixed
+
+			err := c.checkDroppable(tt.username)
+			if tt.wantErr && !errors.Is(err, ErrProtectedUser) {
+				t.Errorf("expected ErrProtectedUser, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package aerospike
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthServiceName is the gRPC health service under which the plugin reports
+// whether it is connected to the Aerospike cluster. The "plugin" service
+// registered by go-plugin only reports that the process is running, and keeps
+// doing so since Vault relies on it.
+const healthServiceName = "aerospike"
+
+// healthUpdateInterval is how often the status of healthServiceName is
+// updated for the clients watching it. Checks always get the current status.
+const healthUpdateInterval = 5 * time.Second
+
+const (
+	healthCheckMethod = "/grpc.health.v1.Health/Check"
+	healthWatchMethod = "/grpc.health.v1.Health/Watch"
+)
+
+// healthService answers the calls to the gRPC health service, including
+// watches, with a health.Server reporting the status of healthServiceName
+// along with the services go-plugin reports. go-plugin registers its own
+// health server, which cannot be replaced nor reached, so the calls are
+// intercepted before they get to it.
+type healthService struct {
+	server *health.Server
+	status func() grpc_health_v1.HealthCheckResponse_ServingStatus
+	stopCh chan struct{}
+}
+
+// newHealthService returns a health service reporting the status of the
+// connection of a, updated every healthUpdateInterval until stop is called.
+func newHealthService(a *Aerospike) *healthService {
+	s := &healthService{
+		server: health.NewServer(),
+		status: a.healthStatus,
+		stopCh: make(chan struct{}),
+	}
+	s.server.SetServingStatus(plugin.GRPCServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	s.update()
+
+	go func() {
+		ticker := time.NewTicker(healthUpdateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+				s.update()
+			}
+		}
+	}()
+
+	return s
+}
+
+// update sets the status of healthServiceName, which is sent to the clients
+// watching it when it changed.
+func (s *healthService) update() {
+	s.server.SetServingStatus(healthServiceName, s.status())
+}
+
+// stop stops updating the status.
+func (s *healthService) stop() {
+	close(s.stopCh)
+}
+
+// unaryInterceptor answers health checks, and passes every other call
+// through.
+func (s *healthService) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if r, ok := req.(*grpc_health_v1.HealthCheckRequest); ok && info.FullMethod == healthCheckMethod {
+		if r.Service == healthServiceName {
+			s.update()
+		}
+		return s.server.Check(ctx, r)
+	}
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor answers health watches, and passes every other call
+// through.
+func (s *healthService) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if info.FullMethod != healthWatchMethod {
+		return handler(srv, ss)
+	}
+
+	req := &grpc_health_v1.HealthCheckRequest{}
+	if err := ss.RecvMsg(req); err != nil {
+		return err
+	}
+	return s.server.Watch(req, &healthWatchStream{ss})
+}
+
+// healthWatchStream is the stream of a health watch.
+type healthWatchStream struct {
+	grpc.ServerStream
+}
+
+func (s *healthWatchStream) Send(resp *grpc_health_v1.HealthCheckResponse) error {
+	return s.SendMsg(resp)
+}
+
+// healthStatus returns SERVING when the client is connected, NOT_SERVING when
+// the plugin is not configured or lost its connection and UNKNOWN when it has
+// not connected yet. It reads the gauges instead of taking
+// the lock, so that health checks are answered while an operation is slow.
+func (c *aerospikeConnectionProducer) healthStatus() grpc_health_v1.HealthCheckResponse_ServingStatus {
+	client := c.gauges.currentClient()
+
+	switch {
+	case !c.gauges.isInitialized():
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	case client == nil:
+		return grpc_health_v1.HealthCheckResponse_UNKNOWN
+	case client.IsConnected():
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	default:
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+}
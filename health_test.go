@@ -0,0 +1,91 @@
+package aerospike
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthService(t *testing.T) {
+	client := newCountingClient()
+	// The health service reads the plugin under the middleware, as in Run
+	db := new()
+	db.client = client
+	db.clientProvided = true
+	db.gauges.setClient(client)
+
+	health := newHealthService(db)
+	defer health.stop()
+
+	// The health server registered by go-plugin stands in for the one the
+	// interceptors have to take over from
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(health.unaryInterceptor),
+		grpc.StreamInterceptor(health.streamInterceptor),
+	)
+	grpc_health_v1.RegisterHealthServer(server, grpc_health_v1.UnimplementedHealthServer{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	healthClient := grpc_health_v1.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	check := func(service string, want grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		resp, err := healthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Status != want {
+			t.Errorf("expected %s to be %s, got %s", service, want, resp.Status)
+		}
+	}
+
+	check("plugin", grpc_health_v1.HealthCheckResponse_SERVING)
+	check(healthServiceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	watch, err := healthClient.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: healthServiceName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv := func(want grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		resp, err := watch.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Status != want {
+			t.Errorf("expected the watch to get %s, got %s", want, resp.Status)
+		}
+	}
+	recv(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	if _, err := db.Init(context.Background(), map[string]interface{}{}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	health.update()
+	recv(grpc_health_v1.HealthCheckResponse_SERVING)
+	check(healthServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	client.disconnect()
+	health.update()
+	recv(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+}
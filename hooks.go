@@ -0,0 +1,47 @@
+package aerospike
+
+import (
+	"context"
+	"time"
+)
+
+// Event describes a successful operation to the hooks. It never holds a
+// password.
+type Event struct {
+	// Username is the user the operation applied to.
+	Username string
+	// Roles are the roles granted to a created user. It is only set for
+	// OnUserCreated.
+	Roles []string
+	// CorrelationID is the correlation ID of the request, if any.
+	CorrelationID string
+	// Time is when the operation completed.
+	Time time.Time
+}
+
+// Hooks are callbacks invoked after successful operations. They are called
+// synchronously while the plugin's lock is held, so they must return quickly
+// and must not call back into the plugin. Nil hooks are skipped.
+type Hooks struct {
+	OnUserCreated     func(Event)
+	OnUserRevoked     func(Event)
+	OnPasswordChanged func(Event)
+	OnRotateRoot      func(Event)
+}
+
+// fireEvent notifies the hook, if set, and the webhook, if configured, that
+// an operation on username succeeded. It must be called with the lock held.
+func (a *Aerospike) fireEvent(ctx context.Context, eventType string, hook func(Event), username string, roles []string) {
+	e := Event{
+		Username:      username,
+		Roles:         roles,
+		CorrelationID: correlationID(ctx),
+		Time:          time.Now(),
+	}
+
+	if hook != nil {
+		hook(e)
+	}
+
+	a.sendWebhook(eventType, e)
+}
@@ -0,0 +1,78 @@
+package aerospike
+
+import (
+	"math/rand"
+	"time"
+)
+
+// splayRand is seeded at startup, unlike the global source of math/rand, so
+// that plugin processes get different delays. It is only used with the lock
+// held.
+var splayRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// startJob runs fn every interval in the background until stopJobs is called.
+// The first run is delayed by a random part of job_splay, so that plugins
+// started at the same time do not run their jobs at the same time. fn is run
+// with the lock held, and never once stopJobs has been called. It must be
+// called with the lock held.
+func (c *aerospikeConnectionProducer) startJob(interval time.Duration, fn func()) {
+	stop := make(chan struct{})
+	c.jobStops = append(c.jobStops, stop)
+	var delay time.Duration
+	if c.jobSplay > 0 {
+		delay = time.Duration(splayRand.Int63n(int64(c.jobSplay)))
+	}
+
+	c.jobsRunning.Add(1)
+	go func() {
+		defer c.jobsRunning.Done()
+
+		if delay > 0 {
+			select {
+			case <-stop:
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !c.runJob(stop, fn) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// runJob runs fn with the lock held, unless stop was closed while waiting for
+// the lock. It reports whether fn was run.
+func (c *aerospikeConnectionProducer) runJob(stop chan struct{}, fn func()) bool {
+	c.lockTimed()
+	defer c.Unlock()
+
+	select {
+	case <-stop:
+		return false
+	default:
+	}
+
+	fn()
+	return true
+}
+
+// stopJobs stops all background jobs, which do not run again once it returns.
+// Their goroutines exit once they get the lock, which jobsRunning waits for.
+// It must be called with the lock held.
+func (c *aerospikeConnectionProducer) stopJobs() {
+	for _, stop := range c.jobStops {
+		close(stop)
+	}
+	c.jobStops = nil
+}
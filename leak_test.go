@@ -0,0 +1,216 @@
+package aerospike
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+	"go.uber.org/goleak"
+)
+
+// countingClient is a Client that records the connections opened and closed
+// on it, and can be disconnected to exercise the reconnect path.
+type countingClient struct {
+	mu        sync.Mutex
+	connected bool
+	opened    int
+	closed    int
+}
+
+func newCountingClient() *countingClient {
+	c := &countingClient{}
+	c.connect()
+	return c
+}
+
+func (c *countingClient) connect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected = true
+	c.opened++
+}
+
+func (c *countingClient) disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected = false
+	c.closed++
+}
+
+func (c *countingClient) counts() (opened, closed int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opened, c.closed
+}
+
+func (c *countingClient) CreateUser(*aerospike.AdminPolicy, string, string, []string) aerospike.Error {
+	return nil
+}
+
+func (c *countingClient) DropUser(*aerospike.AdminPolicy, string) aerospike.Error {
+	return nil
+}
+
+func (c *countingClient) ChangePassword(*aerospike.AdminPolicy, string, string) aerospike.Error {
+	return nil
+}
+
+func (c *countingClient) QueryUser(*aerospike.AdminPolicy, string) (*aerospike.UserRoles, aerospike.Error) {
+	return nil, &aerospike.AerospikeError{ResultCode: types.INVALID_USER}
+}
+
+func (c *countingClient) QueryUsers(*aerospike.AdminPolicy) ([]*aerospike.UserRoles, aerospike.Error) {
+	return nil, nil
+}
+
+func (c *countingClient) QueryRole(*aerospike.AdminPolicy, string) (*aerospike.Role, aerospike.Error) {
+	return &aerospike.Role{}, nil
+}
+
+func (c *countingClient) SetWhitelist(*aerospike.AdminPolicy, string, []string) aerospike.Error {
+	return nil
+}
+
+func (c *countingClient) SetQuotas(*aerospike.AdminPolicy, string, uint32, uint32) aerospike.Error {
+	return nil
+}
+
+func (c *countingClient) Put(*aerospike.WritePolicy, *aerospike.Key, aerospike.BinMap) aerospike.Error {
+	return nil
+}
+
+func (c *countingClient) Stats() (map[string]interface{}, aerospike.Error) {
+	opened, closed := c.counts()
+	return map[string]interface{}{"open-connections": opened - closed}, nil
+}
+
+func (c *countingClient) GetNodes() []*aerospike.Node {
+	return nil
+}
+
+func (c *countingClient) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+func (c *countingClient) Close() {
+	c.disconnect()
+}
+
+// leakConfig starts every background job and the webhook queue, so that
+// Close has them all to stop.
+func leakConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"summary_interval":        "1h",
+		"node_stats_interval":     "1h",
+		"idle_disconnect_timeout": "1h",
+		"webhook_url":             "http://127.0.0.1:1/hook",
+	}
+}
+
+func TestInitCloseLeaks(t *testing.T) {
+	tests := []struct {
+		name             string
+		verifyConnection bool
+	}{
+		{name: "without verification"},
+		{name: "with verification", verifyConnection: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+
+			client := newCountingClient()
+			dbRaw, err := NewWithClient(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := dbRaw.(dbplugin.Database)
+
+			if _, err := db.Init(context.Background(), leakConfig(), tt.verifyConnection); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// A second Init replaces the jobs started by the first
+			if _, err := db.Init(context.Background(), leakConfig(), tt.verifyConnection); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The provided client belongs to the caller
+			if opened, closed := client.counts(); opened != 1 || closed != 0 {
+				t.Errorf("expected 1 connection opened and none closed, got %d and %d", opened, closed)
+			}
+		})
+	}
+}
+
+func TestReconnectLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	client := newCountingClient()
+	dbRaw, err := NewWithClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := dbRaw.(dbplugin.Database)
+
+	if _, err := db.Init(context.Background(), leakConfig(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client.disconnect()
+	err = db.RevokeUser(context.Background(), dbplugin.Statements{}, "v-token-app-1")
+	if err == nil || !strings.Contains(err.Error(), "unable to connect") {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+
+	client.connect()
+	if err := db.RevokeUser(context.Background(), dbplugin.Statements{}, "v-token-app-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opened, closed := client.counts(); opened != 2 || closed != 1 {
+		t.Errorf("expected 2 connections opened and 1 closed, got %d and %d", opened, closed)
+	}
+}
+
+func TestOwnClientReconnectLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := dbRaw.(dbplugin.Database)
+
+	conf := leakConfig()
+	// Nothing listens on port 1, so every connection attempt fails
+	conf["host"] = "127.0.0.1:1"
+	conf["username"] = "admin"
+	conf["password"] = "secret"
+	if _, err := db.Init(context.Background(), conf, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err := db.RevokeUser(context.Background(), dbplugin.Statements{}, "v-token-app-1")
+		if err == nil || !strings.Contains(err.Error(), "unable to connect") {
+			t.Fatalf("expected a connection error, got %v", err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package aerospike
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// configSchemaVersion is the version of the config schema written by this
+// version of the plugin. It must be incremented, and a migration added to
+// configMigrations, whenever a config field is renamed or its meaning
+// changes.
+const configSchemaVersion = 1
+
+// configMigrations upgrades configs to the next schema version, keyed by the
+// version they upgrade from. Configs written before versioning have no
+// version and are treated as version 1.
+var configMigrations = map[int]func(conf map[string]interface{}) error{}
+
+// migrateConfig upgrades conf in place to configSchemaVersion, so that
+// configs stored by older versions of the plugin keep working. The upgraded
+// config is returned by Init and stored by Vault.
+func (c *aerospikeConnectionProducer) migrateConfig(conf map[string]interface{}) error {
+	version := 1
+	if raw, ok := conf["config_schema_version"]; ok {
+		v, err := strconv.Atoi(fmt.Sprint(raw))
+		if err != nil || v < 1 {
+			return fmt.Errorf("invalid config_schema_version %v", raw)
+		}
+		version = v
+	}
+
+	if version > configSchemaVersion {
+		return fmt.Errorf("config_schema_version %d was written by a newer version of the plugin, this version supports up to %d", version, configSchemaVersion)
+	}
+
+	for ; version < configSchemaVersion; version++ {
+		if migrate, ok := configMigrations[version]; ok {
+			if err := migrate(conf); err != nil {
+				return fmt.Errorf("error migrating config from schema version %d: %w", version, err)
+			}
+		}
+		c.logger.Info("migrated config", "from", version, "to", version+1)
+	}
+
+	conf["config_schema_version"] = configSchemaVersion
+
+	return nil
+}
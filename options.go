@@ -0,0 +1,50 @@
+package aerospike
+
+import (
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
+)
+
+// Option customizes the plugin created by New or Run.
+type Option func(*Aerospike)
+
+// WithCredentialsProducer replaces the producer used to generate usernames
+// and passwords. Generated usernames must start with "v-" to be revocable
+// unless allow_unprefixed_drops is set.
+func WithCredentialsProducer(p credsutil.CredentialsProducer) Option {
+	return func(a *Aerospike) {
+		a.CredentialsProducer = p
+	}
+}
+
+// WithStatementParser replaces the parsing of creation statements, for
+// example to support a custom statement dialect.
+func WithStatementParser(p StatementParser) Option {
+	return func(a *Aerospike) {
+		a.statementParser = p
+	}
+}
+
+// WithHooks sets callbacks invoked after successful operations.
+func WithHooks(h Hooks) Option {
+	return func(a *Aerospike) {
+		a.hooks = h
+	}
+}
+
+// WithRevocationStore persists the revocations retried in the background
+// with s, taking precedence over revocation_state_file.
+func WithRevocationStore(s RevocationStore) Option {
+	return func(a *Aerospike) {
+		a.customRevocationStore = s
+	}
+}
+
+// WithClientPolicy sets a function changing the client policy built from the
+// config, to set fields that have no config parameter. It is called every
+// time the policy is built, and must not change the credentials.
+func WithClientPolicy(f func(*aerospike.ClientPolicy)) Option {
+	return func(a *Aerospike) {
+		a.clientPolicyMutator = f
+	}
+}
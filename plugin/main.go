@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
 
 	plugin "github.com/aerospike-community/vault-plugin-database-aerospike"
 	"github.com/hashicorp/vault/api"
@@ -11,11 +12,60 @@ import (
 func main() {
 	apiClientMeta := &api.PluginAPIClientMeta{}
 	flags := apiClientMeta.FlagSet()
+	tlsServerName := flags.String("tls-server-name", "", "")
 	flags.Parse(os.Args[1:])
 
-	err := plugin.Run(apiClientMeta.GetTLSConfig())
+	tlsConfig, err := getTLSConfig(apiClientMeta.GetTLSConfig(), *tlsServerName)
 	if err != nil {
 		log.Println(err)
 		os.Exit(1)
 	}
+
+	err = plugin.Run(tlsConfig)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+// getTLSConfig completes the TLS config used to reach Vault, given by the
+// flags Vault passes to plugins, with -tls-server-name and the standard
+// VAULT_* environment variables for the settings no flag was given for.
+func getTLSConfig(tlsConfig *api.TLSConfig, tlsServerName string) (*api.TLSConfig, error) {
+	if tlsConfig == nil {
+		tlsConfig = &api.TLSConfig{}
+	}
+
+	for _, s := range []struct {
+		value *string
+		env   string
+	}{
+		{&tlsConfig.CACert, api.EnvVaultCACert},
+		{&tlsConfig.CAPath, api.EnvVaultCAPath},
+		{&tlsConfig.ClientCert, api.EnvVaultClientCert},
+		{&tlsConfig.ClientKey, api.EnvVaultClientKey},
+		{&tlsConfig.TLSServerName, api.EnvVaultTLSServerName},
+	} {
+		if *s.value == "" {
+			*s.value = os.Getenv(s.env)
+		}
+	}
+
+	if tlsServerName != "" {
+		tlsConfig.TLSServerName = tlsServerName
+	}
+
+	if v := os.Getenv(api.EnvVaultSkipVerify); v != "" && !tlsConfig.Insecure {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Insecure = insecure
+	}
+
+	if *tlsConfig == (api.TLSConfig{}) {
+		return nil, nil
+	}
+
+	return tlsConfig, nil
 }
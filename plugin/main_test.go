@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// handshakeConfig is the handshake Vault uses with database plugins of
+// protocol version 4. The SDK does not export it.
+var handshakeConfig = plugin.HandshakeConfig{
+	ProtocolVersion:  4,
+	MagicCookieKey:   "VAULT_DATABASE_PLUGIN",
+	MagicCookieValue: "926a0820-aea2-be28-51d6-83cdf00e8edb",
+}
+
+// startPlugin builds the plugin binary and launches it the way Vault does,
+// returning the database it serves over gRPC along with the go-plugin client.
+// The plugin runs in metadata mode, in which it serves without TLS, since
+// there is no Vault to unwrap the TLS material from.
+func startPlugin(t *testing.T) (dbplugin.Database, *plugin.GRPCClient) {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("skipping the build of the plugin binary in short mode")
+	}
+
+	bin := filepath.Join(t.TempDir(), "aerospike-database-plugin")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("unable to build the plugin: %v\n%s", err, out)
+	}
+
+	cmd := exec.Command(bin)
+	cmd.Env = []string{api.PluginMetadataModeEnv + "=true"}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: handshakeConfig,
+		VersionedPlugins: map[int]plugin.PluginSet{
+			4: {"database": new(dbplugin.GRPCDatabasePlugin)},
+		},
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger:           hclog.NewNullLogger(),
+	})
+	t.Cleanup(client.Kill)
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		t.Fatalf("unable to start the plugin: %v", err)
+	}
+	raw, err := rpcClient.Dispense("database")
+	if err != nil {
+		t.Fatalf("unable to dispense the database: %v", err)
+	}
+
+	return raw.(dbplugin.Database), rpcClient.(*plugin.GRPCClient)
+}
+
+func TestPluginGRPC(t *testing.T) {
+	db, rpcClient := startPlugin(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	typ, err := db.Type()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if typ != "aerospike" {
+		t.Errorf("expected type aerospike, got %q", typ)
+	}
+
+	// Nothing listens on port 1, so every operation fails to connect
+	password := "init-secret-password"
+	conf, err := db.Init(ctx, map[string]interface{}{
+		"host":     "127.0.0.1:1",
+		"username": "admin",
+		"password": password,
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf["host"] != "127.0.0.1:1" {
+		t.Errorf("expected the saved config to hold the host, got %v", conf)
+	}
+
+	expiration := time.Now().Add(time.Hour)
+	creation := []string{`{"roles": ["read"]}`}
+
+	operations := []struct {
+		name string
+		run  func() error
+	}{
+		{
+			name: "create",
+			run: func() error {
+				_, _, err := db.CreateUser(ctx, dbplugin.Statements{Creation: creation}, dbplugin.UsernameConfig{DisplayName: "token", RoleName: "app"}, expiration)
+				return err
+			},
+		},
+		{
+			name: "revoke",
+			run: func() error {
+				return db.RevokeUser(ctx, dbplugin.Statements{}, "v-token-app-1")
+			},
+		},
+		{
+			name: "rotate",
+			run: func() error {
+				_, err := db.RotateRootCredentials(ctx, nil)
+				return err
+			},
+		},
+	}
+
+	for _, op := range operations {
+		t.Run(op.name, func(t *testing.T) {
+			err := op.run()
+			if err == nil || !strings.Contains(err.Error(), "unable to connect") {
+				t.Fatalf("expected a connection error, got %v", err)
+			}
+			if strings.Contains(err.Error(), password) {
+				t.Errorf("expected the password to be sanitized from the error, got %v", err)
+			}
+		})
+	}
+
+	// Renewal is a no-op, which needs no connection
+	if err := db.RenewUser(ctx, dbplugin.Statements{}, "v-token-app-1", expiration); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// No connection succeeded, so the connection status is unknown
+	health := grpc_health_v1.NewHealthClient(rpcClient.Conn)
+	for service, want := range map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+		"plugin":    grpc_health_v1.HealthCheckResponse_SERVING,
+		"aerospike": grpc_health_v1.HealthCheckResponse_UNKNOWN,
+	} {
+		resp, err := health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Status != want {
+			t.Errorf("expected %s to be %s, got %s", service, want, resp.Status)
+		}
+	}
+
+	_, err = db.Init(ctx, map[string]interface{}{"host": "127.0.0.1:port"}, false)
+	if err == nil || !strings.Contains(err.Error(), "invalid port number") {
+		t.Errorf("expected an invalid config error, got %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package aerospike
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+)
+
+const (
+	defaultRetryMaxAttempts = 1
+	defaultRetryBaseDelay   = 100 * time.Millisecond
+	defaultRetryMaxDelay    = 2 * time.Second
+)
+
+// retryPolicy controls how admin operations that fail with a transient error
+// are retried. The delay between attempts grows exponentially from baseDelay
+// up to maxDelay, and is reduced by a random fraction of up to jitter.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+}
+
+// do runs op until it succeeds, fails with a non-transient error, the maximum
+// number of attempts is reached or the context is done. op is not run at all
+// if the context is already done.
+func (p retryPolicy) do(ctx context.Context, op func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= p.maxAttempts || !isTransient(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.delay(attempt)):
+		}
+	}
+}
+
+// delay returns how long to wait after the given attempt failed.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	// Compare before shifting, since a large baseDelay would overflow
+	d := p.maxDelay
+	if shift := attempt - 1; shift < 63 && p.baseDelay <= p.maxDelay>>shift {
+		d = p.baseDelay << shift
+	}
+
+	if p.jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.jitter * float64(d))
+	}
+
+	return d
+}
+
+// isUserExists reports whether err is the error returned by the cluster when
+// the user to create already exists.
+func isUserExists(err error) bool {
+	var aerr aerospike.Error
+	return errors.As(err, &aerr) && aerr.Matches(types.USER_ALREADY_EXISTS)
+}
+
+// isTransient reports whether err is an Aerospike error that is worth
+// retrying, such as a timeout or a node being unavailable.
+func isTransient(err error) bool {
+	var aerr aerospike.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	return aerr.Matches(
+		types.TIMEOUT,
+		types.NETWORK_ERROR,
+		types.SERVER_NOT_AVAILABLE,
+		types.NO_AVAILABLE_CONNECTIONS_TO_NODE,
+		types.INVALID_NODE_ERROR,
+	)
+}
@@ -0,0 +1,43 @@
+package aerospike
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  retryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"first attempt", retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}, 1, 100 * time.Millisecond},
+		{"second attempt", retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}, 2, 200 * time.Millisecond},
+		{"fifth attempt", retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}, 5, 1600 * time.Millisecond},
+		{"capped", retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}, 6, 2 * time.Second},
+		{"many attempts", retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}, 100, 2 * time.Second},
+		{"base equals max", retryPolicy{baseDelay: 5 * time.Minute, maxDelay: 5 * time.Minute}, 40, 5 * time.Minute},
+		{"shift would overflow", retryPolicy{baseDelay: 5 * time.Second, maxDelay: 5 * time.Minute}, 32, 5 * time.Minute},
+		{"large base", retryPolicy{baseDelay: time.Hour, maxDelay: 24 * time.Hour}, 35, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.delay(tt.attempt); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	p := retryPolicy{baseDelay: time.Second, maxDelay: time.Minute, jitter: 0.5}
+
+	for attempt := 1; attempt < 10; attempt++ {
+		max := retryPolicy{baseDelay: p.baseDelay, maxDelay: p.maxDelay}.delay(attempt)
+		if got := p.delay(attempt); got <= max/2 || got > max {
+			t.Errorf("attempt %d: expected a delay between %s and %s, got %s", attempt, max/2, max, got)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package aerospike
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RevocationStore persists the users whose revocation is retried in the
+// background, so that the retries survive plugin restarts.
+type RevocationStore interface {
+	// Load returns the usernames saved last.
+	Load() ([]string, error)
+	// Save replaces the saved usernames.
+	Save(usernames []string) error
+}
+
+// fileRevocationStore is the RevocationStore used when
+// revocation_state_file is set. It keeps a JSON array of usernames.
+type fileRevocationStore struct {
+	path string
+}
+
+func (s fileRevocationStore) Load() ([]string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var usernames []string
+	if err := json.Unmarshal(data, &usernames); err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+func (s fileRevocationStore) Save(usernames []string) error {
+	data, err := json.Marshal(usernames)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a crash never leaves a
+	// truncated file behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// loadPendingRevocations adds the users saved in the revocation store to the
+// pending revocations. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) loadPendingRevocations() {
+	if c.revocationStore == nil {
+		return
+	}
+
+	usernames, err := c.revocationStore.Load()
+	if err != nil {
+		c.logger.Error("unable to load pending revocations", "error", err)
+		return
+	}
+
+	if len(usernames) > 0 && c.pendingRevocations == nil {
+		c.pendingRevocations = make(map[string]*pendingRevocation)
+	}
+	for _, username := range usernames {
+		// The config may have changed since the users were saved
+		if err := c.checkDroppable(username); err != nil {
+			c.logger.Warn("ignoring saved pending revocation", "username", username, "error", err)
+			continue
+		}
+		if _, ok := c.pendingRevocations[username]; !ok {
+			c.pendingRevocations[username] = &pendingRevocation{}
+		}
+	}
+	c.gauges.setPendingRevocations(len(c.pendingRevocations))
+}
+
+// savePendingRevocations saves the pending revocations to the revocation
+// store. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) savePendingRevocations() {
+	c.gauges.setPendingRevocations(len(c.pendingRevocations))
+	if c.revocationStore == nil {
+		return
+	}
+
+	usernames := make([]string, 0, len(c.pendingRevocations))
+	for username := range c.pendingRevocations {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	if err := c.revocationStore.Save(usernames); err != nil {
+		c.logger.Error("unable to save pending revocations", "error", err)
+	}
+}
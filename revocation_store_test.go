@@ -0,0 +1,80 @@
+package aerospike
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFileRevocationStore(t *testing.T) {
+	tests := []struct {
+		name      string
+		usernames []string
+		want      []string
+	}{
+		{name: "nothing saved", want: nil},
+		{name: "empty", usernames: []string{}, want: []string{}},
+		{name: "usernames", usernames: []string{"v-a-1", "v-b-2"}, want: []string{"v-a-1", "v-b-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fileRevocationStore{path: filepath.Join(t.TempDir(), "pending.json")}
+
+			if tt.usernames != nil {
+				if err := s.Save(tt.usernames); err != nil {
+					t.Fatalf("unable to save: %v", err)
+				}
+			}
+
+			got, err := s.Load()
+			if err != nil {
+				t.Fatalf("unable to load: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPendingRevocationsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending []string
+		want    []string
+	}{
+		{name: "none", pending: nil, want: []string{}},
+		{name: "sorted", pending: []string{"v-b-2", "v-a-1"}, want: []string{"v-a-1", "v-b-2"}},
+		{name: "protected users are not loaded", pending: []string{"v-a-1", "v-keep", "app"}, want: []string{"v-a-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := fileRevocationStore{path: filepath.Join(t.TempDir(), "pending.json")}
+
+			saver := newTestProducer()
+			saver.revocationStore = store
+			saver.pendingRevocations = map[string]*pendingRevocation{}
+			for _, username := range tt.pending {
+				saver.pendingRevocations[username] = &pendingRevocation{}
+			}
+			saver.savePendingRevocations()
+
+			loader := newTestProducer()
+			loader.revocationStore = store
+			loader.ProtectedUsers = []string{"v-keep"}
+			loader.loadPendingRevocations()
+
+			got := []string{}
+			for username := range loader.pendingRevocations {
+				got = append(got, username)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package aerospike
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+)
+
+const (
+	defaultRevocationRetryMaxAttempts = 10
+	revocationRetryInterval           = 5 * time.Second
+	revocationRetryBaseDelay          = 5 * time.Second
+	revocationRetryMaxDelay           = 5 * time.Minute
+)
+
+// pendingRevocation is a user whose revocation failed with a transient error
+// and is retried in the background.
+type pendingRevocation struct {
+	attempts int
+	next     time.Time
+}
+
+// queueRevocation schedules username to be dropped again in the background
+// if err is transient. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) queueRevocation(username string, err error) {
+	var connErr *ConnectionError
+	if !c.RevocationRetry || (!isTransient(err) && !errors.As(err, &connErr)) {
+		return
+	}
+
+	if c.pendingRevocations == nil {
+		c.pendingRevocations = make(map[string]*pendingRevocation)
+	}
+	if _, ok := c.pendingRevocations[username]; ok {
+		return
+	}
+
+	c.pendingRevocations[username] = &pendingRevocation{
+		attempts: 1,
+		next:     time.Now().Add(c.revocationRetryPolicy().delay(1)),
+	}
+	c.logger.Warn("revocation queued for retry", "username", username, "error", err)
+	c.savePendingRevocations()
+}
+
+// forgetRevocation removes username from the pending revocations, if it is
+// queued. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) forgetRevocation(username string) {
+	if _, ok := c.pendingRevocations[username]; !ok {
+		return
+	}
+	delete(c.pendingRevocations, username)
+	c.savePendingRevocations()
+}
+
+// isUserNotFound reports whether err is the error returned by the cluster
+// when the user to drop does not exist.
+func isUserNotFound(err error) bool {
+	var aerr aerospike.Error
+	return errors.As(err, &aerr) && aerr.Matches(types.INVALID_USER)
+}
+
+func (c *aerospikeConnectionProducer) revocationRetryPolicy() retryPolicy {
+	p := retryPolicy{
+		maxAttempts: c.RevocationRetryMaxAttempts,
+		baseDelay:   revocationRetryBaseDelay,
+		maxDelay:    revocationRetryMaxDelay,
+	}
+	if p.maxAttempts == 0 {
+		p.maxAttempts = defaultRevocationRetryMaxAttempts
+	}
+	return p
+}
+
+// retryRevocations drops the pending users whose next attempt is due. Users
+// that no longer exist are considered revoked, and users that must not be
+// dropped, per checkDroppable, are removed from the queue. It must be called
+// with the lock held.
+func (c *aerospikeConnectionProducer) retryRevocations() {
+	defer c.releaseConnection()
+
+	policy := c.revocationRetryPolicy()
+	now := time.Now()
+	changed := false
+
+	for username, p := range c.pendingRevocations {
+		if now.Before(p.next) {
+			continue
+		}
+
+		err := c.dropPendingUser(username)
+		p.attempts++
+
+		switch {
+		case err == nil, isUserNotFound(err):
+			delete(c.pendingRevocations, username)
+			changed = true
+			c.logger.Info("queued revocation succeeded", "username", username, "attempts", p.attempts)
+		case errors.Is(err, ErrProtectedUser):
+			delete(c.pendingRevocations, username)
+			changed = true
+			c.logger.Error("dropping queued revocation", "username", username, "error", err)
+		case p.attempts >= policy.maxAttempts:
+			delete(c.pendingRevocations, username)
+			changed = true
+			c.counters.record("revoke_user_retry", err)
+			c.logger.Error("giving up queued revocation", "username", username, "attempts", p.attempts, "error", err)
+		default:
+			p.next = now.Add(policy.delay(p.attempts))
+			c.logger.Warn("queued revocation failed", "username", username, "attempts", p.attempts, "error", err)
+		}
+	}
+
+	if changed {
+		c.savePendingRevocations()
+	}
+}
+
+func (c *aerospikeConnectionProducer) dropPendingUser(username string) error {
+	if err := c.checkDroppable(username); err != nil {
+		return err
+	}
+
+	client, err := c.Connection(context.Background())
+	if err != nil {
+		return err
+	}
+	if err := client.(Client).DropUser(aerospike.NewAdminPolicy(), username); err != nil {
+		return err
+	}
+
+	c.countDynamicUser(username, -1)
+	c.writeAuditRecord(context.Background(), username, aerospike.BinMap{
+		"username": username,
+		"revoked":  auditTime(time.Now()),
+	})
+	return nil
+}
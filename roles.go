@@ -0,0 +1,157 @@
+package aerospike
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// knownPrivileges lists the privilege codes understood by the plugin. The
+// privileges introduced with Aerospike 6 (truncate, sindex-admin and
+// udf-admin) are not, since the client cannot decode them.
+var knownPrivileges = map[string]bool{
+	string(aerospike.UserAdmin):    true,
+	string(aerospike.SysAdmin):     true,
+	string(aerospike.DataAdmin):    true,
+	string(aerospike.ReadWriteUDF): true,
+	string(aerospike.ReadWrite):    true,
+	string(aerospike.Read):         true,
+	string(aerospike.Write):        true,
+}
+
+// checkPrivileges looks up the privileges granted by roles and returns an
+// error if any of them is not part of the allowed privileges.
+func (c *aerospikeConnectionProducer) checkPrivileges(client Client, roles []string) error {
+	allowed := make(map[string]bool, len(c.AllowedPrivileges))
+	for _, p := range c.AllowedPrivileges {
+		allowed[p] = true
+	}
+
+	for _, name := range roles {
+		role, ok := c.roleCache.get(name)
+		if !ok {
+			var err error
+			role, err = c.queryRole(client, name)
+			if err != nil {
+				return fmt.Errorf("unable to look up role %q: %w", name, err)
+			}
+			c.roleCache.put(name, role)
+		}
+
+		for _, p := range role.Privileges {
+			if !allowed[string(p.Code)] {
+				return fmt.Errorf("%w: role %q grants the %s privilege, which is not in allowed_privileges", ErrPrivilegeNotAllowed, name, p.Code)
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryRole looks up a role. The client panics when a role holds a privilege
+// it does not know about, such as the ones introduced with Aerospike 6, so the
+// panic is turned into an ErrUnknownPrivilege error. The client is closed
+// then, since the connection it panicked on may be left with an unread
+// response, and is replaced on the next call to Connection: callers must stop
+// using it. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) queryRole(client Client, name string) (role *aerospike.Role, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrUnknownPrivilege, r)
+			if c.clientProvided {
+				c.logger.Warn("the provided client may have to be reconnected after failing to decode a role", "role", name)
+			}
+			c.closeClient()
+		}
+	}()
+
+	return client.QueryRole(aerospike.NewAdminPolicy(), name)
+}
+
+// roleCache keeps the roles looked up from the server for a limited time, so
+// that creating many users with the same roles does not query them every time.
+// It must be used with the producer lock held.
+type roleCache struct {
+	ttl     time.Duration
+	entries map[string]roleCacheEntry
+}
+
+type roleCacheEntry struct {
+	role    *aerospike.Role
+	expires time.Time
+}
+
+func newRoleCache(ttl time.Duration) *roleCache {
+	return &roleCache{
+		ttl:     ttl,
+		entries: map[string]roleCacheEntry{},
+	}
+}
+
+func (rc *roleCache) get(name string) (*aerospike.Role, bool) {
+	if rc == nil {
+		return nil, false
+	}
+
+	e, ok := rc.entries[name]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.role, true
+}
+
+func (rc *roleCache) put(name string, role *aerospike.Role) {
+	if rc == nil || rc.ttl <= 0 {
+		return
+	}
+
+	rc.entries[name] = roleCacheEntry{
+		role:    role,
+		expires: time.Now().Add(rc.ttl),
+	}
+}
+
+// clear drops all entries, which is done whenever an operation fails since
+// the failure may be caused by stale roles.
+func (rc *roleCache) clear() {
+	if rc == nil {
+		return
+	}
+
+	rc.entries = map[string]roleCacheEntry{}
+}
+
+// checkAdminPrivileges returns an error if the plugin's own user is not
+// granted the user-admin privilege, which every operation needs.
+func (c *aerospikeConnectionProducer) checkAdminPrivileges(client Client) error {
+	user, err := client.QueryUser(aerospike.NewAdminPolicy(), c.Username)
+	if err != nil {
+		return fmt.Errorf("unable to look up user %s: %w", c.Username, err)
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", c.Username)
+	}
+
+	for _, name := range user.Roles {
+		if name == AdminRole {
+			return nil
+		}
+
+		role, err := c.queryRole(client, name)
+		if errors.Is(err, ErrUnknownPrivilege) {
+			return fmt.Errorf("unable to look up role %q: %w", name, err)
+		}
+		if err != nil {
+			continue
+		}
+		for _, p := range role.Privileges {
+			if p.Code == aerospike.UserAdmin {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("user %s is not granted the %s privilege, which is needed to manage users: grant it the %s role", c.Username, aerospike.UserAdmin, AdminRole)
+}
@@ -0,0 +1,38 @@
+package aerospike
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// undecodableRoleClient panics when looking up roles, like the client does
+// with roles holding privileges it does not know about.
+type undecodableRoleClient struct {
+	*countingClient
+	roleQueries int
+}
+
+func (c *undecodableRoleClient) QueryUser(*aerospike.AdminPolicy, string) (*aerospike.UserRoles, aerospike.Error) {
+	return &aerospike.UserRoles{User: "admin", Roles: []string{"custom", "other"}}, nil
+}
+
+func (c *undecodableRoleClient) QueryRole(*aerospike.AdminPolicy, string) (*aerospike.Role, aerospike.Error) {
+	c.roleQueries++
+	panic("unknown privilege")
+}
+
+func TestCheckAdminPrivilegesUnknownPrivilege(t *testing.T) {
+	client := &undecodableRoleClient{countingClient: newCountingClient()}
+	c := newTestProducer()
+	c.Username = "admin"
+
+	err := c.checkAdminPrivileges(client)
+	if !errors.Is(err, ErrUnknownPrivilege) {
+		t.Fatalf("expected ErrUnknownPrivilege, got %v", err)
+	}
+	if client.roleQueries != 1 {
+		t.Errorf("expected the client not to be used after failing, got %d role queries", client.roleQueries)
+	}
+}
@@ -0,0 +1,22 @@
+package aerospike
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// closeOnTerminate closes db and exits when the process receives SIGTERM, as
+// container runtimes send on shutdown, so that the connections to the cluster
+// are closed cleanly.
+func closeOnTerminate(db *Aerospike) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM)
+
+	go func() {
+		<-ch
+		db.logger.Info("received SIGTERM, shutting down")
+		db.Close()
+		os.Exit(0)
+	}()
+}
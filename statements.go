@@ -0,0 +1,199 @@
+package aerospike
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+type aerospikeCreationStatement struct {
+	Roles []string `json:"roles"`
+	// Bundle names a bundle of role_bundles whose roles are granted in
+	// addition to Roles.
+	Bundle string `json:"bundle"`
+}
+
+// aerospikeRotationStatement updates the settings of existing roles when the
+// password of a static user is rotated.
+type aerospikeRotationStatement struct {
+	Quotas map[string]roleQuotas `json:"quotas"`
+	// Whitelists maps role names to the addresses allowed to use them. An
+	// empty list allows all addresses.
+	Whitelists map[string][]string `json:"whitelists"`
+}
+
+// roleQuotas are the read and write quotas of a role, in records per second.
+// Zero means unlimited.
+type roleQuotas struct {
+	Read  uint32 `json:"read"`
+	Write uint32 `json:"write"`
+}
+
+// statementOverrides are optional fields of creation and revocation
+// statements overriding the config for a single operation.
+type statementOverrides struct {
+	// TimeoutMs replaces both the timeout of the operation and the timeout
+	// of each admin command it sends.
+	TimeoutMs int `json:"timeout_ms"`
+	// MaxAttempts replaces retry_max_attempts.
+	MaxAttempts int `json:"max_attempts"`
+}
+
+// parseOverrides parses the overrides of the first of the statements, which
+// are JSON objects. No statement, or one that is not a JSON object, such as
+// the statements Vault fills in by default, means no overrides.
+func parseOverrides(statements []string) (statementOverrides, error) {
+	var o statementOverrides
+	if len(statements) == 0 || !isJSONObject(statements[0]) {
+		return o, nil
+	}
+
+	if err := json.Unmarshal([]byte(statements[0]), &o); err != nil {
+		return o, &kindError{kind: ErrInvalidStatement, err: err}
+	}
+
+	if o.TimeoutMs < 0 || o.MaxAttempts < 0 {
+		return o, fmt.Errorf("%w: timeout_ms and max_attempts cannot be negative", ErrInvalidStatement)
+	}
+
+	return o, nil
+}
+
+// isJSONObject reports whether statement looks like a JSON object, as opposed
+// to a statement meant for another parser.
+func isJSONObject(statement string) bool {
+	return strings.HasPrefix(strings.TrimSpace(statement), "{")
+}
+
+// timeout returns the timeout of the operation, d unless overridden.
+func (o statementOverrides) timeout(d time.Duration) time.Duration {
+	if o.TimeoutMs > 0 {
+		return time.Duration(o.TimeoutMs) * time.Millisecond
+	}
+	return d
+}
+
+// retryPolicy returns the retry policy of the operation, p unless
+// overridden.
+func (o statementOverrides) retryPolicy(p retryPolicy) retryPolicy {
+	if o.MaxAttempts > 0 {
+		p.maxAttempts = o.MaxAttempts
+	}
+	return p
+}
+
+// context returns ctx carrying the timeout of the admin commands, when
+// overridden.
+func (o statementOverrides) context(ctx context.Context) context.Context {
+	return withAdminTimeout(ctx, o.timeout(0))
+}
+
+// StatementParser turns a creation statement into the roles granted to the
+// user being created. Errors that do not match ErrInvalidStatement are
+// wrapped so that they do.
+type StatementParser interface {
+	ParseCreationStatement(statement string) (roles []string, err error)
+}
+
+// jsonStatementParser is the default StatementParser, which expects a JSON
+// object with a roles array, a bundle name, or both. The roles of the bundle
+// are added by the caller, since they depend on the config.
+type jsonStatementParser struct{}
+
+func (p jsonStatementParser) ParseCreationStatement(statement string) ([]string, error) {
+	cs, err := p.parse(statement)
+	if err != nil {
+		return nil, err
+	}
+	return cs.Roles, nil
+}
+
+func (jsonStatementParser) parse(statement string) (aerospikeCreationStatement, error) {
+	var cs aerospikeCreationStatement
+	if err := json.Unmarshal([]byte(statement), &cs); err != nil {
+		return cs, &kindError{kind: ErrInvalidStatement, err: err}
+	}
+
+	if len(cs.Roles) == 0 && cs.Bundle == "" {
+		return cs, fmt.Errorf("%w: roles array or bundle is required in creation statement", ErrInvalidStatement)
+	}
+
+	return cs, nil
+}
+
+// maxStatementCacheEntries bounds the number of parsed creation statements
+// that are cached. The cache is emptied when it is full.
+const maxStatementCacheEntries = 256
+
+// parseCreationStatement parses statement with the configured parser. Results
+// of the default parser are cached by the hash of the statement, since the
+// same statements are parsed for every lease of a role. Only statements of the
+// default dialect can reference a bundle. It must be called with the lock
+// held.
+func (a *Aerospike) parseCreationStatement(statement string) (aerospikeCreationStatement, error) {
+	parser, ok := a.statementParser.(jsonStatementParser)
+	if !ok {
+		roles, err := a.statementParser.ParseCreationStatement(statement)
+		if err != nil && !errors.Is(err, ErrInvalidStatement) {
+			err = &kindError{kind: ErrInvalidStatement, err: err}
+		}
+		return aerospikeCreationStatement{Roles: roles}, err
+	}
+
+	key := sha256.Sum256([]byte(statement))
+	if cs, ok := a.statementCache[key]; ok {
+		return cs, nil
+	}
+
+	cs, err := parser.parse(statement)
+	if err != nil {
+		return cs, err
+	}
+
+	if a.statementCache == nil || len(a.statementCache) >= maxStatementCacheEntries {
+		a.statementCache = make(map[[sha256.Size]byte]aerospikeCreationStatement)
+	}
+	a.statementCache[key] = cs
+
+	return cs, nil
+}
+
+// parseRotationStatements parses the rotation statements of a static role.
+// Statements that are not JSON objects, such as the ones Vault fills in by
+// default, are ignored.
+func parseRotationStatements(statements []string) ([]aerospikeRotationStatement, error) {
+	result := make([]aerospikeRotationStatement, 0, len(statements))
+	for _, statement := range statements {
+		if !isJSONObject(statement) {
+			continue
+		}
+
+		var rs aerospikeRotationStatement
+		if err := json.Unmarshal([]byte(statement), &rs); err != nil {
+			return nil, &kindError{kind: ErrInvalidStatement, err: err}
+		}
+		result = append(result, rs)
+	}
+	return result, nil
+}
+
+// applyRotationStatement updates the roles as described by rs.
+func applyRotationStatement(client Client, policy *aerospike.AdminPolicy, rs aerospikeRotationStatement) error {
+	for role, q := range rs.Quotas {
+		if err := client.SetQuotas(policy, role, q.Read, q.Write); err != nil {
+			return fmt.Errorf("unable to set quotas of role %s: %w", role, err)
+		}
+	}
+	for role, whitelist := range rs.Whitelists {
+		if err := client.SetWhitelist(policy, role, whitelist); err != nil {
+			return fmt.Errorf("unable to set whitelist of role %s: %w", role, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package aerospike
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+		want       statementOverrides
+		wantErr    bool
+	}{
+		{name: "no statement"},
+		{name: "empty statement", statements: []string{""}},
+		{name: "not JSON", statements: []string{"DROP USER {{name}}"}},
+		{name: "JSON array", statements: []string{`["read"]`}},
+		{
+			name:       "overrides",
+			statements: []string{`{"timeout_ms": 5000, "max_attempts": 3}`},
+			want:       statementOverrides{TimeoutMs: 5000, MaxAttempts: 3},
+		},
+		{
+			name:       "leading whitespace",
+			statements: []string{"\n  {\"max_attempts\": 2}"},
+			want:       statementOverrides{MaxAttempts: 2},
+		},
+		{
+			name:       "only the first statement",
+			statements: []string{`{"roles": ["read"]}`, `{"timeout_ms": 5000}`},
+		},
+		{name: "invalid JSON", statements: []string{`{"timeout_ms": }`}, wantErr: true},
+		{name: "negative timeout", statements: []string{`{"timeout_ms": -1}`}, wantErr: true},
+		{name: "negative attempts", statements: []string{`{"max_attempts": -1}`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOverrides(tt.statements)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStatement) {
+					t.Fatalf("expected ErrInvalidStatement, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRotationStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+		want       []aerospikeRotationStatement
+		wantErr    bool
+	}{
+		{name: "no statement", want: []aerospikeRotationStatement{}},
+		{name: "not JSON", statements: []string{"", "ALTER ROLE app"}, want: []aerospikeRotationStatement{}},
+		{
+			name:       "quotas",
+			statements: []string{`{"quotas": {"app": {"read": 100, "write": 10}}}`},
+			want:       []aerospikeRotationStatement{{Quotas: map[string]roleQuotas{"app": {Read: 100, Write: 10}}}},
+		},
+		{
+			name:       "whitelists among other statements",
+			statements: []string{"ALTER ROLE app", `{"whitelists": {"app": ["10.0.0.0/8"]}}`},
+			want:       []aerospikeRotationStatement{{Whitelists: map[string][]string{"app": {"10.0.0.0/8"}}}},
+		},
+		{name: "invalid JSON", statements: []string{`{"quotas": `}, wantErr: true},
+		{name: "invalid quotas", statements: []string{`{"quotas": {"app": {"read": -1}}}`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRotationStatements(tt.statements)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStatement) {
+					t.Fatalf("expected ErrInvalidStatement, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
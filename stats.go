@@ -0,0 +1,45 @@
+package aerospike
+
+import (
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// logNodeStats measures the latency of an info command sent to each node of
+// the cluster and logs it along with the client's connection statistics for
+// that node. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) logNodeStats() {
+	client := c.client
+	if client == nil || !client.IsConnected() {
+		return
+	}
+
+	stats, err := client.Stats()
+	if err != nil {
+		c.logger.Warn("unable to get client statistics", "error", err)
+	}
+
+	for _, node := range client.GetNodes() {
+		start := time.Now()
+		_, err := node.RequestInfo(aerospike.NewInfoPolicy(), "node")
+		args := []interface{}{
+			"node", node.GetName(),
+			"address", node.GetHost().String(),
+			"latency", time.Since(start),
+		}
+
+		if s, ok := stats[node.GetHost().String()].(map[string]interface{}); ok {
+			args = append(args,
+				"open_connections", s["open-connections"],
+				"failed_connections", s["connections-failed"],
+			)
+		}
+
+		if err != nil {
+			c.logger.Warn("node statistics", append(args, "error", err)...)
+			continue
+		}
+		c.logger.Info("node statistics", args...)
+	}
+}
@@ -0,0 +1,125 @@
+package aerospike
+
+import (
+	"errors"
+	"sync"
+)
+
+// opCounters counts operations between two summary log lines. A nil
+// opCounters counts nothing.
+type opCounters struct {
+	mu              sync.Mutex
+	usersCreated    int
+	usersRevoked    int
+	passwordChanges int
+	connects        int
+	errors          map[string]int
+
+	// totals counts the outcomes of each operation since the plugin
+	// started, keyed by "<operation>.<outcome>". It is never reset.
+	totals map[string]uint64
+}
+
+func newOpCounters() *opCounters {
+	return &opCounters{
+		errors: make(map[string]int),
+		totals: make(map[string]uint64),
+	}
+}
+
+// record counts the outcome of operation.
+func (o *opCounters) record(operation string, err error) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err != nil {
+		o.errors[errorClass(err)]++
+		o.totals[operation+".failure"]++
+		return
+	}
+	o.totals[operation+".success"]++
+
+	switch operation {
+	case "create_user":
+		o.usersCreated++
+	case "revoke_user":
+		o.usersRevoked++
+	case "set_credentials", "rotate_root_credentials":
+		o.passwordChanges++
+	}
+}
+
+// connected counts a new client.
+func (o *opCounters) connected() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	o.connects++
+	o.mu.Unlock()
+}
+
+// snapshot returns the totals as a JSON-encodable value.
+func (o *opCounters) snapshot() interface{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	totals := make(map[string]uint64, len(o.totals))
+	for k, v := range o.totals {
+		totals[k] = v
+	}
+	return totals
+}
+
+// errorClass returns a short name for the kind of err, used to group errors
+// in the summary.
+func errorClass(err error) string {
+	var connErr *ConnectionError
+	switch {
+	case errors.Is(err, ErrInvalidStatement):
+		return "invalid_statement"
+	case errors.Is(err, ErrPrivilegeNotAllowed), errors.Is(err, ErrProtectedUser), errors.Is(err, ErrReservedUsername), errors.Is(err, ErrStaticUserNotAllowed), errors.Is(err, ErrReadOnly), errors.Is(err, ErrMaintenance), errors.Is(err, ErrAdminRestricted):
+		return "policy"
+	case errors.Is(err, ErrNotInitialized):
+		return "not_initialized"
+	case errors.As(err, &connErr):
+		return "connection"
+	case isTransient(err):
+		return "transient"
+	default:
+		return "other"
+	}
+}
+
+// logSummary logs the counters and resets them. It must be called with the
+// lock held.
+func (c *aerospikeConnectionProducer) logSummary() {
+	o := c.counters
+	if o == nil {
+		return
+	}
+
+	pending := len(c.pendingRevocations)
+
+	o.mu.Lock()
+	args := []interface{}{
+		"users_created", o.usersCreated,
+		"users_revoked", o.usersRevoked,
+		"password_changes", o.passwordChanges,
+		"connects", o.connects,
+		"pending_revocations", pending,
+	}
+	for class, n := range o.errors {
+		args = append(args, "errors_"+class, n)
+	}
+	o.usersCreated, o.usersRevoked, o.passwordChanges, o.connects = 0, 0, 0, 0
+	o.errors = make(map[string]int)
+	o.mu.Unlock()
+
+	c.logger.Info("operations summary", args...)
+}
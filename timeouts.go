@@ -0,0 +1,70 @@
+package aerospike
+
+import (
+	"context"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// withTimeout returns a context that is done after d, or ctx itself with a
+// cancel function when d is not set.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// adminTimeoutKey is the context key of the timeout of admin commands set by
+// withAdminTimeout.
+type adminTimeoutKey struct{}
+
+// withAdminTimeout returns a context setting the timeout of the admin
+// commands sent with it to d, or ctx itself when d is not set.
+func withAdminTimeout(ctx context.Context, d time.Duration) context.Context {
+	if d <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, adminTimeoutKey{}, d)
+}
+
+// adminPolicy returns an admin policy whose timeout does not go past the
+// deadline of ctx, using the timeout set by withAdminTimeout if any.
+func adminPolicy(ctx context.Context) *aerospike.AdminPolicy {
+	policy := aerospike.NewAdminPolicy()
+	if d, ok := ctx.Value(adminTimeoutKey{}).(time.Duration); ok {
+		policy.Timeout = d
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining < time.Millisecond {
+			// A zero timeout would mean the default one
+			remaining = time.Millisecond
+		}
+		if remaining < policy.Timeout {
+			policy.Timeout = remaining
+		}
+	}
+
+	return policy
+}
+
+// writePolicy returns a write policy whose total timeout does not go past the
+// deadline of ctx.
+func writePolicy(ctx context.Context) *aerospike.WritePolicy {
+	policy := aerospike.NewWritePolicy(0, 0)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining < time.Millisecond {
+			remaining = time.Millisecond
+		}
+		if policy.TotalTimeout == 0 || remaining < policy.TotalTimeout {
+			policy.TotalTimeout = remaining
+		}
+	}
+
+	return policy
+}
@@ -0,0 +1,337 @@
+package aerospike
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// defaultTLSExpiryThresholdDays is the number of days before the expiry of a
+// certificate from which Init warns about it.
+const defaultTLSExpiryThresholdDays = 30
+
+// getTLSConfig parses the TLSCAData and TLSCertificateKeyData byte slices and
+// builds a tls.Config. TLS is enabled when a CA is given or the system cert
+// pool is used.
+func (c *aerospikeConnectionProducer) getTLSConfig() (*tls.Config, error) {
+	if len(c.TLSCAData) == 0 && !c.TLSUseSystemRoots {
+		// A client certificate would otherwise be silently ignored
+		if len(c.TLSCertificateKeyData) > 0 || c.TLSPKCS12 != "" {
+			return nil, fmt.Errorf("tls_certificate_key and tls_pkcs12 require tls_ca or tls_use_system_roots to be set, since TLS is not used otherwise")
+		}
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs: x509.NewCertPool(),
+	}
+
+	if c.TLSUseSystemRoots {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the system cert pool: %w", err)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if len(c.TLSCAData) > 0 {
+		ok := tlsConfig.RootCAs.AppendCertsFromPEM(c.TLSCAData)
+		if !ok {
+			return nil, fmt.Errorf("failed to append CA to client policy")
+		}
+	}
+
+	if len(c.TLSCertificateKeyData) > 0 {
+		certificate, err := tls.X509KeyPair(c.TLSCertificateKeyData, c.TLSCertificateKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls_certificate_key_data: %w", err)
+		}
+
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+
+	tlsConfig.SessionTicketsDisabled = c.TLSDisableSessionTickets
+	if c.TLSSessionCacheSize < 0 {
+		return nil, fmt.Errorf("tls_session_cache_size cannot be negative")
+	}
+	if c.TLSSessionCacheSize > 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(c.TLSSessionCacheSize)
+	}
+
+	revocationCheck, err := c.revocationCheck()
+	if err != nil {
+		return nil, err
+	}
+
+	pinningCheck, err := c.pinningCheck()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+			return fmt.Errorf("no verified certificate chain")
+		}
+		for _, check := range []func(tls.ConnectionState) error{revocationCheck, pinningCheck} {
+			if check == nil {
+				continue
+			}
+			if err := check(cs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if c.TLSPKCS12 != "" {
+		if len(c.TLSCertificateKeyData) > 0 {
+			return nil, fmt.Errorf("tls_certificate_key and tls_pkcs12 cannot both be set")
+		}
+
+		certificate, err := c.loadPKCS12()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls_pkcs12: %w", err)
+		}
+
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+
+	return tlsConfig, nil
+}
+
+// loadPKCS12 decodes the base64 encoded PKCS#12 bundle holding the client
+// certificate, its chain and its private key. Only bundles encrypted with the
+// legacy algorithms (3DES or RC2) can be decoded.
+func (c *aerospikeConnectionProducer) loadPKCS12() (tls.Certificate, error) {
+	data, err := base64.StdEncoding.DecodeString(c.TLSPKCS12)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	blocks, err := pkcs12.ToPEM(data, c.TLSPKCS12Password)
+	if err != nil {
+		var notImplemented pkcs12.NotImplementedError
+		if errors.As(err, &notImplemented) {
+			return tls.Certificate{}, fmt.Errorf("%w; only bundles encrypted with 3DES or RC2 are supported, re-export it with openssl pkcs12 -export -legacy", err)
+		}
+		return tls.Certificate{}, err
+	}
+
+	return keyPairFromPEM(blocks)
+}
+
+// keyPairFromPEM builds the client certificate from the certificates and
+// private key of a PKCS#12 bundle. The bundle does not order its
+// certificates, so the client certificate is the one matching the key.
+func keyPairFromPEM(blocks []*pem.Block) (tls.Certificate, error) {
+	var certs []*pem.Block
+	var keyPEM []byte
+	for _, b := range blocks {
+		if b.Type == "CERTIFICATE" {
+			certs = append(certs, b)
+		} else {
+			keyPEM = append(keyPEM, pem.EncodeToMemory(b)...)
+		}
+	}
+
+	// X509KeyPair takes the first certificate as the leaf
+	for i, leaf := range certs {
+		certPEM := pem.EncodeToMemory(leaf)
+		for j, b := range certs {
+			if j != i {
+				certPEM = append(certPEM, pem.EncodeToMemory(b)...)
+			}
+		}
+
+		if certificate, err := tls.X509KeyPair(certPEM, keyPEM); err == nil {
+			return certificate, nil
+		}
+	}
+
+	return tls.Certificate{}, errors.New("no certificate in the bundle matches its private key")
+}
+
+// checkTLSNames warns when TLS names are given for the hosts without TLS,
+// since they are then ignored. Hosts without a TLS name are fine: the client
+// validates the server certificate against the host name in that case.
+func (c *aerospikeConnectionProducer) checkTLSNames() {
+	if c.clientPolicy.TlsConfig != nil {
+		return
+	}
+
+	for i, h := range c.hosts {
+		if h.TLSName != "" {
+			c.warn("host #%d has a TLS name but neither tls_ca nor tls_use_system_roots is set, so TLS is not used", i+1)
+		}
+	}
+}
+
+// checkCertificates checks that the configured certificates have not expired
+// and warns, or fails if TLSExpiryFail is set, when one of them expires within
+// TLSExpiryThresholdDays. It also warns when the client certificate does not
+// chain to the configured CA.
+func (c *aerospikeConnectionProducer) checkCertificates() error {
+	tlsConfig := c.clientPolicy.TlsConfig
+	if tlsConfig == nil {
+		return nil
+	}
+
+	threshold := c.TLSExpiryThresholdDays
+	if threshold == 0 {
+		threshold = defaultTLSExpiryThresholdDays
+	}
+	deadline := time.Now().AddDate(0, 0, threshold)
+
+	check := func(name string, cert *x509.Certificate) error {
+		switch {
+		case time.Now().After(cert.NotAfter):
+			return fmt.Errorf("%s %q expired on %s", name, cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		case deadline.After(cert.NotAfter) && c.TLSExpiryFail:
+			return fmt.Errorf("%s %q expires on %s, within %d days", name, cert.Subject, cert.NotAfter.Format(time.RFC3339), threshold)
+		case deadline.After(cert.NotAfter):
+			c.warn("%s %q expires on %s, within %d days", name, cert.Subject, cert.NotAfter.Format(time.RFC3339), threshold)
+		}
+		return nil
+	}
+
+	for rest := c.TLSCAData; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("unable to parse tls_ca: %w", err)
+		}
+		if err := check("CA certificate", cert); err != nil {
+			return err
+		}
+	}
+
+	if len(tlsConfig.Certificates) == 0 {
+		return nil
+	}
+
+	chain := tlsConfig.Certificates[0].Certificate
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return fmt.Errorf("unable to parse the client certificate: %w", err)
+	}
+	if err := check("client certificate", leaf); err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, der := range chain[1:] {
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         tlsConfig.RootCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		c.warn("the client certificate could not be verified against tls_ca, make sure the servers trust its issuer: %v", err)
+	}
+
+	return nil
+}
+
+// revocationCheck returns a check failing when the server certificate was
+// revoked, according to its stapled OCSP response when TLSRequireOCSPStapling
+// is set and to the CRL given by TLSCRL. It returns nil when neither is set.
+// The CRL is kept in c.crl, for refreshCRL to load it again.
+func (c *aerospikeConnectionProducer) revocationCheck() (func(tls.ConnectionState) error, error) {
+	c.crl = nil
+	if !c.TLSRequireOCSPStapling && c.TLSCRL == "" {
+		return nil, nil
+	}
+
+	var crl *crlChecker
+	if c.TLSCRL != "" {
+		var err error
+		crl, err = newCRLChecker(c.TLSCRL, c.logger)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls_crl: %w", err)
+		}
+		if crl.crl.HasExpired(time.Now()) {
+			c.warn("the CRL loaded from tls_crl is past its next update time")
+		}
+		c.crl = crl
+	}
+
+	requireOCSP := c.TLSRequireOCSPStapling
+	return func(cs tls.ConnectionState) error {
+		chain := cs.VerifiedChains[0]
+		leaf := chain[0]
+
+		if crl != nil {
+			if err := crl.check(chain); err != nil {
+				return err
+			}
+		}
+
+		if requireOCSP {
+			if len(cs.OCSPResponse) == 0 {
+				return fmt.Errorf("server did not staple an OCSP response")
+			}
+
+			var issuer *x509.Certificate
+			if len(chain) > 1 {
+				issuer = chain[1]
+			}
+			resp, err := ocsp.ParseResponseForCert(cs.OCSPResponse, leaf, issuer)
+			if err != nil {
+				return fmt.Errorf("invalid stapled OCSP response: %w", err)
+			}
+			if resp.Status != ocsp.Good {
+				return fmt.Errorf("server certificate %q is not valid according to its OCSP response", leaf.Subject)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+// pinningCheck returns a check failing when the public key of the server
+// certificate does not match one of the SPKI hashes of TLSPinnedSPKI. It
+// returns nil when no pin is configured.
+func (c *aerospikeConnectionProducer) pinningCheck() (func(tls.ConnectionState) error, error) {
+	if len(c.TLSPinnedSPKI) == 0 {
+		return nil, nil
+	}
+
+	pins := map[string]bool{}
+	for _, pin := range c.TLSPinnedSPKI {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid pin %q in tls_pinned_spki, expected a base64 encoded SHA-256 hash", pin)
+		}
+		pins[string(hash)] = true
+	}
+
+	return func(cs tls.ConnectionState) error {
+		leaf := cs.VerifiedChains[0][0]
+		hash := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+		if !pins[string(hash[:])] {
+			return fmt.Errorf("public key of server certificate %q does not match tls_pinned_spki", leaf.Subject)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,112 @@
+package aerospike
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a certificate for commonName signed by parent, or
+// self-signed when parent is nil, and its private key.
+func testCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestKeyPairFromPEM(t *testing.T) {
+	ca, caKey := testCertificate(t, "test-ca", nil, nil)
+	leaf, leafKey := testCertificate(t, "vault", ca, caKey)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caBlock := &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}
+	leafBlock := &pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}
+	keyBlock := &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}
+
+	tests := []struct {
+		name    string
+		blocks  []*pem.Block
+		wantErr bool
+	}{
+		{name: "leaf first", blocks: []*pem.Block{leafBlock, caBlock, keyBlock}},
+		{name: "chain first", blocks: []*pem.Block{caBlock, leafBlock, keyBlock}},
+		{name: "key first", blocks: []*pem.Block{keyBlock, caBlock, leafBlock}},
+		{name: "no matching certificate", blocks: []*pem.Block{caBlock, keyBlock}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certificate, err := keyPairFromPEM(tt.blocks)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(certificate.Certificate) != 2 || string(certificate.Certificate[0]) != string(leaf.Raw) {
+				t.Errorf("expected the client certificate followed by its chain")
+			}
+		})
+	}
+}
+
+func TestGetTLSConfigClientIdentityWithoutTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		conf func(c *aerospikeConnectionProducer)
+	}{
+		{name: "certificate and key", conf: func(c *aerospikeConnectionProducer) { c.TLSCertificateKeyData = []byte("pem") }},
+		{name: "PKCS#12 bundle", conf: func(c *aerospikeConnectionProducer) { c.TLSPKCS12 = "bundle" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestProducer()
+			tt.conf(c)
+
+			_, err := c.getTLSConfig()
+			if err == nil || !strings.Contains(err.Error(), "require tls_ca or tls_use_system_roots") {
+				t.Errorf("expected an error about TLS not being used, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package aerospike
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+)
+
+// dynamicUserCountTTL is how long the number of users generated by Vault is
+// cached. The plugin's own operations keep the cached count up to date, so it
+// only drifts when such users are created or dropped by other means.
+const dynamicUserCountTTL = time.Minute
+
+// userCount is the cached number of users generated by Vault.
+type userCount struct {
+	n       int
+	expires time.Time
+}
+
+// checkUserCap returns ErrTooManyUsers if max_dynamic_users is set and that
+// many users generated by Vault already exist.
+func (c *aerospikeConnectionProducer) checkUserCap(client Client, policy *aerospike.AdminPolicy) error {
+	if c.MaxDynamicUsers <= 0 {
+		return nil
+	}
+
+	if time.Now().After(c.dynamicUsers.expires) {
+		users, err := client.QueryUsers(policy)
+		if err != nil {
+			return err
+		}
+
+		n := 0
+		for _, u := range users {
+			if strings.HasPrefix(u.User, UsernamePrefix) {
+				n++
+			}
+		}
+		c.dynamicUsers = userCount{n: n, expires: time.Now().Add(dynamicUserCountTTL)}
+	}
+
+	if c.dynamicUsers.n >= c.MaxDynamicUsers {
+		return fmt.Errorf("%w: %d users generated by Vault exist, max_dynamic_users is %d", ErrTooManyUsers, c.dynamicUsers.n, c.MaxDynamicUsers)
+	}
+
+	return nil
+}
+
+// countDynamicUser adjusts the cached number of users generated by Vault after
+// username was created (delta 1) or dropped (delta -1).
+func (c *aerospikeConnectionProducer) countDynamicUser(username string, delta int) {
+	if !strings.HasPrefix(username, UsernamePrefix) {
+		return
+	}
+
+	c.dynamicUsers.n += delta
+	if c.dynamicUsers.n < 0 {
+		c.dynamicUsers.n = 0
+	}
+}
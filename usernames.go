@@ -0,0 +1,128 @@
+package aerospike
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
+)
+
+// UsernamePrefix starts the usernames generated by the plugin, and by
+// credsutil.GenerateUsername, with the default "-" separator. It marks the
+// users managed by Vault.
+const UsernamePrefix = "v-"
+
+const (
+	// maxUsernameLength is the longest username Aerospike accepts.
+	// See https://www.aerospike.com/docs/guide/limitations.html
+	maxUsernameLength = 63
+	// defaultUsernameRandomLength is the length of the random part of
+	// generated usernames when username_random_length is not set, reduced to
+	// fit username_max_length.
+	defaultUsernameRandomLength = 20
+	// minUsernameRandomLength is the shortest random part of generated
+	// usernames, which keeps them unique. It is also the shortest length
+	// credsutil.RandomAlphaNumeric accepts.
+	minUsernameRandomLength = 10
+	// usernameFixedLength is the length of the parts of generated usernames
+	// other than the names and the random part: "v", the separators around
+	// the random part and the 10 digit timestamp.
+	usernameFixedLength = 1 + 1 + 1 + 10
+	// minUsernameLength is the shortest username_max_length allowed, which
+	// holds the shortest random part.
+	minUsernameLength = usernameFixedLength + minUsernameRandomLength
+)
+
+// usernameLength returns the maximum length of generated usernames.
+func (c *aerospikeConnectionProducer) usernameLength() int {
+	if c.UsernameMaxLength > 0 {
+		return c.UsernameMaxLength
+	}
+	return maxUsernameLength
+}
+
+// usernameRandomLength returns the length of the random part of generated
+// usernames.
+func (c *aerospikeConnectionProducer) usernameRandomLength() int {
+	if c.UsernameRandomLength > 0 {
+		return c.UsernameRandomLength
+	}
+	if max := c.usernameLength() - usernameFixedLength; max < defaultUsernameRandomLength {
+		return max
+	}
+	return defaultUsernameRandomLength
+}
+
+// generateUsername generates a username for a dynamic user. The default
+// producer is adjusted to the configured maximum length and random part
+// length, custom producers are used as is.
+func (a *Aerospike) generateUsername(displayName, roleName string) (string, error) {
+	p, ok := a.CredentialsProducer.(*credsutil.SQLCredentialsProducer)
+	if !ok {
+		return a.GenerateUsername(dbplugin.UsernameConfig{DisplayName: displayName, RoleName: roleName})
+	}
+
+	return a.buildUsername(truncate(displayName, p.DisplayNameLen), truncate(roleName, p.RoleNameLen), p.Separator, p.LowercaseUsername)
+}
+
+// buildUsername builds a username in the v-<display>-<role>-<random>-<time>
+// format of credsutil.GenerateUsername. Unlike it, the display and role names
+// are shortened rather than the random part and the time when the username
+// is too long, so that generated usernames stay unique.
+func (c *aerospikeConnectionProducer) buildUsername(displayName, roleName, separator string, lowercase bool) (string, error) {
+	n := c.usernameRandomLength()
+	if max := c.usernameLength() - usernameFixedLength - 2*(len(separator)-1); n > max {
+		n = max
+	}
+	if n < minUsernameRandomLength {
+		return "", fmt.Errorf("username_max_length %d leaves no room for the random part of usernames", c.usernameLength())
+	}
+
+	random, err := credsutil.RandomAlphaNumeric(n, false)
+	if err != nil {
+		return "", err
+	}
+
+	suffix := separator + random + separator + strconv.FormatInt(time.Now().Unix(), 10)
+	prefix := strings.TrimSuffix(UsernamePrefix, "-")
+	for _, name := range []string{displayName, roleName} {
+		if name != "" {
+			prefix += separator + name
+		}
+	}
+
+	if max := c.usernameLength() - len(suffix); len(prefix) > max {
+		prefix = strings.TrimSuffix(prefix[:max], separator)
+	}
+
+	username := prefix + suffix
+	if lowercase {
+		username = strings.ToLower(username)
+	}
+	return username, nil
+}
+
+// checkUsernameRandomLength returns an error if username_random_length does
+// not leave room for the rest of the username.
+func (c *aerospikeConnectionProducer) checkUsernameRandomLength() error {
+	if c.UsernameRandomLength == 0 {
+		return nil
+	}
+
+	max := c.usernameLength() - usernameFixedLength
+	if c.UsernameRandomLength < minUsernameRandomLength || c.UsernameRandomLength > max {
+		return fmt.Errorf("username_random_length must be between %d and %d", minUsernameRandomLength, max)
+	}
+
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if n > 0 && len(s) > n {
+		return s[:n]
+	}
+	return s
+}
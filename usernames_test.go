@@ -0,0 +1,107 @@
+package aerospike
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateUsername(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxLength    int
+		randomLength int
+		displayName  string
+		roleName     string
+		wantPrefix   string
+		wantRandom   int
+	}{
+		{
+			name:        "default",
+			displayName: "token",
+			roleName:    "as-reader",
+			wantPrefix:  "v-token-as-reader-",
+			wantRandom:  20,
+		},
+		{
+			name:        "long names are truncated by the producer",
+			displayName: "a-very-long-display-name",
+			roleName:    "a-very-long-role-name",
+			wantPrefix:  "v-a-very-long-dis-a-very-long-r-",
+			wantRandom:  20,
+		},
+		{
+			name:        "names are shortened to fit",
+			maxLength:   43,
+			displayName: "token",
+			roleName:    "as-reader",
+			wantPrefix:  "v-token-as-",
+			wantRandom:  20,
+		},
+		{
+			name:        "random part reduced to fit",
+			maxLength:   30,
+			displayName: "token",
+			roleName:    "as-reader",
+			wantPrefix:  "v-",
+			wantRandom:  17,
+		},
+		{
+			name:        "shortest username",
+			maxLength:   minUsernameLength,
+			displayName: "token",
+			roleName:    "as-reader",
+			wantPrefix:  "v-",
+			wantRandom:  minUsernameRandomLength,
+		},
+		{
+			name:         "configured random length",
+			randomLength: 12,
+			displayName:  "token",
+			roleName:     "as-reader",
+			wantPrefix:   "v-token-as-reader-",
+			wantRandom:   12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := new()
+			a.UsernameMaxLength = tt.maxLength
+			a.UsernameRandomLength = tt.randomLength
+
+			username, err := a.generateUsername(tt.displayName, tt.roleName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(username) > a.usernameLength() {
+				t.Errorf("%s is longer than %d", username, a.usernameLength())
+			}
+			if !strings.HasPrefix(username, tt.wantPrefix) {
+				t.Errorf("expected %s to start with %s", username, tt.wantPrefix)
+			}
+
+			parts := strings.Split(username, "-")
+			if n := len(parts); n < 3 || len(parts[n-1]) != 10 || len(parts[n-2]) != tt.wantRandom {
+				t.Errorf("expected %s to end with a random part of %d characters and a timestamp", username, tt.wantRandom)
+			}
+		})
+	}
+}
+
+func TestGenerateUsernameUnique(t *testing.T) {
+	a := new()
+	a.UsernameMaxLength = minUsernameLength
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		username, err := a.generateUsername("a-long-display-name", "a-long-role-name")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if seen[username] {
+			t.Fatalf("%s was generated twice", username)
+		}
+		seen[username] = true
+	}
+}
@@ -0,0 +1,190 @@
+package aerospike
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aerospike/aerospike-client-go/v5"
+	"github.com/aerospike/aerospike-client-go/v5/types"
+	"github.com/hashicorp/go-hclog"
+)
+
+// verifySet and verifyKey identify the record read, or written and deleted,
+// to check that a user has access to a namespace. The record does not need to
+// exist.
+const (
+	verifySet = "vault"
+	verifyKey = "vault-verify"
+)
+
+const (
+	defaultVerifyTimeout = 10 * time.Second
+	verifyPollInterval   = 500 * time.Millisecond
+
+	// verifyRecordTTL is the expiration of the record written to check write
+	// access, in case it cannot be deleted.
+	verifyRecordTTL = 60
+)
+
+// ErrPasswordNotPropagated is returned when a changed password could not be
+// used to log into every node of the cluster within the verification timeout.
+var ErrPasswordNotPropagated = errors.New("password change did not take effect on the whole cluster")
+
+// ErrHollowGrant is returned when a new user can log in, but its roles do not
+// let it access the verification namespace.
+var ErrHollowGrant = errors.New("granted roles do not give access to the verification namespace")
+
+// credentialPolicy returns a copy of the client policy using the given
+// credentials. Users managed by the plugin are internal users, whatever the
+// authentication mode of the plugin's own user.
+func (c *aerospikeConnectionProducer) credentialPolicy(username, password string) *aerospike.ClientPolicy {
+	policy := *c.clientPolicy
+	policy.User = username
+	policy.Password = password
+	policy.AuthMode = aerospike.AuthModeInternal
+	return &policy
+}
+
+// seedHosts returns the configured hosts, or the hosts of the nodes of the
+// provided client when none are configured.
+func (c *aerospikeConnectionProducer) seedHosts() []*aerospike.Host {
+	if len(c.hosts) > 0 || c.client == nil {
+		return c.hosts
+	}
+
+	var hosts []*aerospike.Host
+	for _, node := range c.client.GetNodes() {
+		hosts = append(hosts, node.GetHost())
+	}
+	return hosts
+}
+
+// verifyLogin checks that username can log in with password on one of the
+// seed hosts.
+func (c *aerospikeConnectionProducer) verifyLogin(username, password string) error {
+	return loginAny(c.credentialPolicy(username, password), c.seedHosts())
+}
+
+// loginAny checks that the user of policy can log in on one of hosts.
+func loginAny(policy *aerospike.ClientPolicy, hosts []*aerospike.Host) error {
+	if len(hosts) == 0 {
+		return fmt.Errorf("unable to log in as %s: no host to connect to", policy.User)
+	}
+
+	var err error
+	for _, host := range hosts {
+		if err = requestInfo(policy, host); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unable to log in as %s: %w", policy.User, err)
+}
+
+// verifyAccess checks that the user of policy can read from namespace, by
+// connecting to hosts as that user and checking whether a record exists, and
+// when write is set, that it can also write to it, by writing and deleting a
+// record. Failures caused by the roles of the user are reported as
+// ErrHollowGrant.
+func verifyAccess(logger hclog.Logger, policy *aerospike.ClientPolicy, hosts []*aerospike.Host, namespace string, write bool) error {
+	username := policy.User
+	if len(hosts) == 0 {
+		return fmt.Errorf("unable to connect as %s: no host to connect to", username)
+	}
+
+	client, err := aerospike.NewClientWithPolicyAndHost(policy, hosts...)
+	if err != nil {
+		return fmt.Errorf("unable to connect as %s: %w", username, err)
+	}
+	defer client.Close()
+
+	key, err := aerospike.NewKey(namespace, verifySet, verifyKey)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.Exists(nil, key); err != nil {
+		return accessError(fmt.Errorf("%s cannot read from namespace %s: %w", username, namespace, err))
+	}
+
+	if !write {
+		return nil
+	}
+
+	writePolicy := aerospike.NewWritePolicy(0, verifyRecordTTL)
+	if err := client.Put(writePolicy, key, aerospike.BinMap{"user": username}); err != nil {
+		return accessError(fmt.Errorf("%s cannot write to namespace %s: %w", username, namespace, err))
+	}
+
+	if _, err := client.Delete(nil, key); err != nil {
+		logger.Warn("unable to delete verification record", "namespace", namespace, "set", verifySet, "error", err)
+	}
+
+	return nil
+}
+
+// accessError classifies err as ErrHollowGrant when it is caused by the roles
+// of the user.
+func accessError(err error) error {
+	var aerr aerospike.Error
+	if errors.As(err, &aerr) && aerr.Matches(types.ROLE_VIOLATION) {
+		return &kindError{kind: ErrHollowGrant, err: err}
+	}
+	return err
+}
+
+// verifyPropagation waits until username can log into every node of the
+// cluster with password, for at most the verification timeout.
+func (c *aerospikeConnectionProducer) verifyPropagation(ctx context.Context, username, password string) error {
+	client, err := c.Connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy := c.credentialPolicy(username, password)
+
+	var failed []string
+	err = pollVerification(ctx, c.verifyTimeout, func() error {
+		failed = nil
+		var lastErr error
+		for _, node := range client.(Client).GetNodes() {
+			if err := requestInfo(policy, node.GetHost()); err != nil {
+				failed = append(failed, node.GetName())
+				lastErr = err
+			}
+		}
+		return lastErr
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return fmt.Errorf("%w: %v", ErrPasswordNotPropagated, ctx.Err())
+	default:
+		return fmt.Errorf("%w: %s cannot log into node(s) %v after %s: %v", ErrPasswordNotPropagated, username, failed, c.verifyTimeout, err)
+	}
+}
+
+// pollVerification runs check until it succeeds, for at most timeout, waiting
+// verifyPollInterval between attempts, since changes to users take a moment
+// to reach every node of the cluster. It returns the last error of check, or
+// the error of ctx when it is done first.
+func pollVerification(ctx context.Context, timeout time.Duration, check func() error) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(verifyPollInterval):
+		}
+	}
+}
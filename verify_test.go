@@ -0,0 +1,52 @@
+package aerospike
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollVerification(t *testing.T) {
+	errLogin := errors.New("login failed")
+
+	tests := []struct {
+		name         string
+		failures     int
+		timeout      time.Duration
+		cancel       bool
+		wantErr      error
+		wantAttempts int
+	}{
+		{name: "first attempt", timeout: time.Second, wantAttempts: 1},
+		{name: "second attempt", failures: 1, timeout: time.Second, wantAttempts: 2},
+		{name: "timeout", failures: 5, wantErr: errLogin, wantAttempts: 1},
+		{name: "context done", failures: 5, timeout: time.Minute, cancel: true, wantErr: context.Canceled, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			attempts := 0
+			err := pollVerification(ctx, tt.timeout, func() error {
+				attempts++
+				if attempts <= tt.failures {
+					return errLogin
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package aerospike
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	defaultWebhookMaxAttempts = 3
+	defaultWebhookTimeout     = 10 * time.Second
+	webhookBaseDelay          = time.Second
+	webhookMaxDelay           = 30 * time.Second
+
+	// webhookQueueSize bounds the number of events waiting to be delivered.
+	// Events are dropped when the queue is full.
+	webhookQueueSize = 100
+)
+
+// Webhook event types.
+const (
+	eventUserCreated     = "user_created"
+	eventUserRevoked     = "user_revoked"
+	eventPasswordChanged = "password_changed"
+	eventRootRotated     = "root_rotated"
+)
+
+// webhookPayload is the JSON body posted to the webhook.
+type webhookPayload struct {
+	Event         string    `json:"event"`
+	Username      string    `json:"username"`
+	Roles         []string  `json:"roles,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// webhookDelivery is an event waiting to be posted, along with the webhook
+// config at the time it happened.
+type webhookDelivery struct {
+	url        string
+	authHeader string
+	timeout    time.Duration
+	policy     retryPolicy
+	eventType  string
+	username   string
+	body       []byte
+}
+
+// webhookQueue delivers the events one at a time from a single goroutine, so
+// that a slow or unreachable webhook cannot pile up goroutines.
+type webhookQueue struct {
+	deliveries chan webhookDelivery
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	logger     hclog.Logger
+}
+
+func newWebhookQueue(logger hclog.Logger) *webhookQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &webhookQueue{
+		deliveries: make(chan webhookDelivery, webhookQueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		logger:     logger,
+	}
+	go q.run()
+	return q
+}
+
+func (q *webhookQueue) run() {
+	defer close(q.done)
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			if n := len(q.deliveries); n > 0 {
+				q.logger.Warn("dropping undelivered webhook events", "count", n)
+			}
+			return
+		case d := <-q.deliveries:
+			q.deliver(d)
+		}
+	}
+}
+
+// deliver posts d, retrying with an exponential backoff until it is accepted,
+// the maximum number of attempts is reached or the queue is stopped.
+func (q *webhookQueue) deliver(d webhookDelivery) {
+	client := &http.Client{Timeout: d.timeout}
+
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(q.ctx, client, d.url, d.authHeader, d.body)
+		if err == nil {
+			return
+		}
+		if attempt >= d.policy.maxAttempts || q.ctx.Err() != nil {
+			q.logger.Error("unable to deliver webhook", "event", d.eventType, "username", d.username, "attempts", attempt, "error", err)
+			return
+		}
+
+		select {
+		case <-q.ctx.Done():
+		case <-time.After(d.policy.delay(attempt)):
+		}
+	}
+}
+
+// stop cancels the delivery in progress, drops the queued events and waits
+// for the goroutine to exit.
+func (q *webhookQueue) stop() {
+	q.cancel()
+	<-q.done
+}
+
+// sendWebhook queues the event to be posted to WebhookURL in the background.
+// It must be called with the lock held.
+func (c *aerospikeConnectionProducer) sendWebhook(eventType string, e Event) {
+	if c.WebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:         eventType,
+		Username:      e.Username,
+		Roles:         e.Roles,
+		CorrelationID: e.CorrelationID,
+		Time:          e.Time,
+	})
+	if err != nil {
+		c.logger.Error("unable to encode webhook payload", "error", err)
+		return
+	}
+
+	d := webhookDelivery{
+		url:        c.WebhookURL,
+		authHeader: c.WebhookAuthHeader,
+		timeout:    c.webhookTimeout,
+		policy: retryPolicy{
+			maxAttempts: c.WebhookMaxAttempts,
+			baseDelay:   webhookBaseDelay,
+			maxDelay:    webhookMaxDelay,
+		},
+		eventType: eventType,
+		username:  e.Username,
+		body:      body,
+	}
+	if d.policy.maxAttempts == 0 {
+		d.policy.maxAttempts = defaultWebhookMaxAttempts
+	}
+
+	if c.webhooks == nil {
+		c.webhooks = newWebhookQueue(c.logger)
+	}
+
+	select {
+	case c.webhooks.deliveries <- d:
+	default:
+		c.logger.Error("webhook queue is full, dropping event", "event", eventType, "username", e.Username)
+	}
+}
+
+// stopWebhooks stops the delivery of webhook events, dropping the ones not
+// delivered yet. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) stopWebhooks() {
+	if c.webhooks == nil {
+		return
+	}
+
+	c.webhooks.stop()
+	c.webhooks = nil
+}
+
+func postWebhook(ctx context.Context, client *http.Client, url, authHeader string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package aerospike
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// timeWindow is a daily time range, restricted to some days of the week. A
+// range ending before it starts goes past midnight, and days then refer to the
+// day it starts.
+type timeWindow struct {
+	days       [7]bool
+	start, end int // minutes since midnight
+}
+
+// parseTimeWindow parses a window written "[<days>] <HH:MM>-<HH:MM>", where
+// days is a comma separated list of day names or ranges, such as "mon-fri" or
+// "sat,sun". Every day is included when days is omitted.
+func parseTimeWindow(s string) (timeWindow, error) {
+	var w timeWindow
+
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		for d := range w.days {
+			w.days[d] = true
+		}
+	case 2:
+		for _, r := range strings.Split(strings.ToLower(fields[0]), ",") {
+			bounds := strings.SplitN(r, "-", 2)
+			first, ok := weekdays[bounds[0]]
+			if !ok {
+				return w, fmt.Errorf("invalid day %q in window %q", bounds[0], s)
+			}
+			last := first
+			if len(bounds) == 2 {
+				if last, ok = weekdays[bounds[1]]; !ok {
+					return w, fmt.Errorf("invalid day %q in window %q", bounds[1], s)
+				}
+			}
+			for d := first; ; d = (d + 1) % 7 {
+				w.days[d] = true
+				if d == last {
+					break
+				}
+			}
+		}
+	default:
+		return w, fmt.Errorf("invalid window %q, must be [<days>] <HH:MM>-<HH:MM>", s)
+	}
+
+	times := strings.SplitN(fields[len(fields)-1], "-", 2)
+	if len(times) != 2 {
+		return w, fmt.Errorf("invalid window %q, must be [<days>] <HH:MM>-<HH:MM>", s)
+	}
+
+	var err error
+	if w.start, err = parseClock(times[0]); err != nil {
+		return w, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	if w.end, err = parseClock(times[1]); err != nil {
+		return w, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+
+	return w, nil
+}
+
+// parseClock parses a HH:MM time of day into minutes since midnight. 24:00 is
+// accepted as the end of the day.
+func parseClock(s string) (int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, must be HH:MM", s)
+	}
+
+	h, herr := strconv.Atoi(parts[0])
+	m, merr := strconv.Atoi(parts[1])
+	if herr != nil || merr != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q, must be HH:MM", s)
+	}
+
+	return h*60 + m, nil
+}
+
+// contains reports whether t is within the window.
+func (w timeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+
+	if w.start <= w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+
+	// The window goes past midnight
+	if minute >= w.start {
+		return w.days[t.Weekday()]
+	}
+	return minute < w.end && w.days[(t.Weekday()+6)%7]
+}
+
+// inJobWindows returns fn wrapped so that it only runs within the job windows,
+// if any are configured. It must be called with the lock held.
+func (c *aerospikeConnectionProducer) inJobWindows(name string, fn func()) func() {
+	windows, location, logger := c.jobWindows, c.jobWindowsLocation, c.logger
+	if len(windows) == 0 {
+		return fn
+	}
+
+	return func() {
+		now := time.Now().In(location)
+		for _, w := range windows {
+			if w.contains(now) {
+				fn()
+				return
+			}
+		}
+		logger.Debug("skipping job outside of job_windows", "job", name)
+	}
+}